@@ -21,6 +21,9 @@ func main() {
 	}
 
 	err = cethacea.RootCmd.Execute()
+	if cethacea.Settings.Debug {
+		cethacea.PrintRequestStats()
+	}
 	if err != nil {
 		log.Fatalf("%++v", err)
 	}
@@ -1,6 +1,7 @@
 package cethacea
 
 import (
+	"bufio"
 	"context"
 	"crypto/ecdsa"
 	"crypto/rand"
@@ -8,11 +9,17 @@ import (
 	"fmt"
 	"github.com/elek/cethacea/pkg/config"
 	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/secp256k1"
 	"github.com/ktr0731/go-fuzzyfinder"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
 )
 
 var DefaultAccountFileName = ".accounts.yaml"
@@ -29,13 +36,112 @@ func init() {
 		Use:     "generate <name>",
 		Aliases: []string{"g"},
 		Args:    cobra.MaximumNArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
+	}
+	encrypted := generateCmd.Flags().Bool("keystore", false, "Store the private key (or mnemonic) encrypted (Web3 Secret Storage format)")
+	light := generateCmd.Flags().Bool("light", false, "Use less memory and CPU for key encryption (less secure)")
+	mnemonic := generateCmd.Flags().Bool("mnemonic", false, "Generate BIP-39 mnemonic (HD wallet) instead of a single key")
+	words := generateCmd.Flags().Int("words", 12, "Number of words in the generated mnemonic (12 or 24)")
+	generateCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		f, err := config.NewAccountRepo("")
+		if err != nil {
+			return err
+		}
+		if *mnemonic {
+			return generateMnemonicAccount(f, *words, *encrypted, *light)
+		}
+		return generateAccount(f, *encrypted, *light)
+	}
+	{
+		importMnemonicCmd := cobra.Command{
+			Use:   "import-mnemonic <name>",
+			Short: "Import HD wallet from BIP-39 mnemonic (read from the standard input)",
+			Args:  cobra.MaximumNArgs(1),
+		}
+		path := importMnemonicCmd.Flags().String("path", config.DefaultDerivationPath, "BIP-32 derivation path of the account")
+		encrypted := importMnemonicCmd.Flags().Bool("keystore", false, "Store the mnemonic encrypted (Web3 Secret Storage format)")
+		light := importMnemonicCmd.Flags().Bool("light", false, "Use less memory and CPU for mnemonic encryption (less secure)")
+		importMnemonicCmd.RunE = func(cmd *cobra.Command, args []string) error {
 			f, err := config.NewAccountRepo("")
 			if err != nil {
 				return err
 			}
-			return generateAccount(f)
-		},
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			return importMnemonic(f, name, *path, *encrypted, *light)
+		}
+		accountCmd.AddCommand(&importMnemonicCmd)
+	}
+	{
+		externalCmd := cobra.Command{
+			Use:   "external <name> <address> <signer-url>",
+			Short: "Register account where the signing is done by an external (Clef compatible) signer",
+			Args:  cobra.ExactArgs(3),
+		}
+		externalCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			f, err := config.NewAccountRepo("")
+			if err != nil {
+				return err
+			}
+			return addExternalAccount(f, args[0], args[1], args[2])
+		}
+		accountCmd.AddCommand(&externalCmd)
+	}
+	{
+		deriveCmd := cobra.Command{
+			Use:   "derive <name>",
+			Short: "List addresses derived from a mnemonic based account (use them as <name>/<index>)",
+			Args:  cobra.ExactArgs(1),
+		}
+		count := deriveCmd.Flags().Int("count", 10, "Number of the derived addresses to show")
+		deriveCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return deriveAccounts(ceth.AccountRepo, args[0], *count)
+		}
+		accountCmd.AddCommand(&deriveCmd)
+	}
+	{
+		importCmd := cobra.Command{
+			Use:   "import <keystore-file> <name>",
+			Short: "Import encrypted private key from a keystore (Web3 Secret Storage) file",
+			Args:  cobra.RangeArgs(1, 2),
+		}
+		importCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			f, err := config.NewAccountRepo("")
+			if err != nil {
+				return err
+			}
+			name := ""
+			if len(args) > 1 {
+				name = args[1]
+			}
+			return importAccount(f, args[0], name)
+		}
+		accountCmd.AddCommand(&importCmd)
+	}
+	{
+		exportCmd := cobra.Command{
+			Use:   "export <name> <keystore-file>",
+			Short: "Export private key to a keystore (Web3 Secret Storage) file",
+			Args:  cobra.RangeArgs(1, 2),
+		}
+		light := exportCmd.Flags().Bool("light", false, "Use less memory and CPU for key encryption (less secure)")
+		exportCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			f, err := config.NewAccountRepo("")
+			if err != nil {
+				return err
+			}
+			file := ""
+			if len(args) > 1 {
+				file = args[1]
+			}
+			return exportAccount(f, args[0], file, *light)
+		}
+		accountCmd.AddCommand(&exportCmd)
 	}
 	{
 		listCmd := cobra.Command{
@@ -85,6 +191,21 @@ func init() {
 		}
 		accountCmd.AddCommand(&infoCmd)
 	}
+	{
+		nonceCmd := cobra.Command{
+			Use:   "nonce",
+			Short: "Show the locally reserved nonces of the current account (and the nonce gaps)",
+		}
+		reset := nonceCmd.Flags().Bool("reset", false, "Drop the local reservations (next transaction uses the pending nonce of the node)")
+		nonceCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return accountNonce(ceth, *reset)
+		}
+		accountCmd.AddCommand(&nonceCmd)
+	}
 	accountCmd.AddCommand(&generateCmd)
 	accountCmd.AddCommand(&switchCmd)
 	RootCmd.AddCommand(&accountCmd)
@@ -111,6 +232,75 @@ func accountInfo(ceth *Ceth) error {
 	return PrintItem(info, ceth.Settings.Format)
 }
 
+func accountNonce(ceth *Ceth, reset bool) error {
+	ctx := context.Background()
+
+	c, err := ceth.GetClient()
+	if err != nil {
+		return err
+	}
+	account, err := ceth.AccountRepo.GetCurrentAccount()
+	if err != nil {
+		return err
+	}
+	address := account.Address()
+	chainID, err := c.GetChainID(ctx)
+	if err != nil {
+		return err
+	}
+	mined, err := c.Client.NonceAt(ctx, address, nil)
+	if err != nil {
+		return err
+	}
+	pending, err := c.Client.PendingNonceAt(ctx, address)
+	if err != nil {
+		return err
+	}
+
+	nonces, err := ceth.GetNonceManager()
+	if err != nil {
+		return err
+	}
+	if reset {
+		err = nonces.Reset(chainID, address)
+		if err != nil {
+			return err
+		}
+	}
+	state, err := nonces.State(chainID, address)
+	if err != nil {
+		return err
+	}
+
+	item := types.Item{}
+	item.AddField("account", address.Hex())
+	item.AddField("chainId", chainID)
+	item.AddField("nonce", mined)
+	item.AddField("pendingNonce", pending)
+	next := state.Next
+	if next < pending {
+		next = pending
+	}
+	item.AddField("nextNonce", next)
+	var reserved []uint64
+	for _, n := range state.Reserved() {
+		if n >= pending {
+			reserved = append(reserved, n)
+		}
+	}
+	item.AddField("reserved", nonceList(reserved))
+	item.AddField("gaps", nonceList(state.Gaps(pending, nonces.ReservationTimeout)))
+	return PrintItem(item, ceth.Settings.Format)
+}
+
+func nonceList(nonces []uint64) string {
+	var res []string
+	for _, n := range nonces {
+		res = append(res, strconv.FormatUint(n, 10))
+	}
+	return strings.Join(res, ",")
+}
+
 func switchAccount(ceth *Ceth, s string) error {
 	f := ceth.AccountRepo
 	if s == "" {
@@ -135,7 +325,7 @@ func switchAccount(ceth *Ceth, s string) error {
 	return listAccounts(f, false)
 }
 
-func generateAccount(f *config.AccountRepo) error {
+func generateAccount(f *config.AccountRepo, encrypted bool, light bool) error {
 	name := f.GetNextName()
 	key, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
 	if err != nil {
@@ -146,10 +336,225 @@ func generateAccount(f *config.AccountRepo) error {
 		Name:    name,
 		Private: hex.EncodeToString(crypto.FromECDSA(key)),
 	}
+	if encrypted {
+		pwd, err := config.ReadPassword("Passphrase", true)
+		if err != nil {
+			return err
+		}
+		scryptN, scryptP := scryptParams(light)
+		a, err = config.EncryptAccount(name, key, pwd, scryptN, scryptP)
+		if err != nil {
+			return err
+		}
+	}
 	err = f.AddAccount(a)
 	return err
 }
 
+func generateMnemonicAccount(f *config.AccountRepo, words int, encrypted bool, light bool) error {
+	if words != 12 && words != 24 {
+		return errors.New("Mnemonic should have 12 or 24 words")
+	}
+	mnemonic, err := config.NewMnemonic(words)
+	if err != nil {
+		return err
+	}
+	a, err := config.MnemonicAccount(f.GetNextName(), mnemonic, config.DefaultDerivationPath)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		a, err = encryptMnemonic(a, light)
+		if err != nil {
+			return err
+		}
+	}
+	err = f.AddAccount(a)
+	if err != nil {
+		return err
+	}
+	fmt.Println(a.Name + " " + mnemonic)
+	return nil
+}
+
+func importMnemonic(f *config.AccountRepo, name string, path string, encrypted bool, light bool) error {
+	if name == "" {
+		name = f.GetNextName()
+	}
+	exists, err := f.AccountExists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.Errorf("Account %s already exists", name)
+	}
+
+	fmt.Fprintln(os.Stderr, "Mnemonic:")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return errors.Wrap(err, "Couldn't read mnemonic")
+	}
+	a, err := config.MnemonicAccount(name, line, path)
+	if err != nil {
+		return err
+	}
+	derived, err := config.DeriveAccount(a, -1)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		a, err = encryptMnemonic(a, light)
+		if err != nil {
+			return err
+		}
+	}
+	err = f.AddAccount(a)
+	if err != nil {
+		return err
+	}
+	fmt.Println(name + " " + derived.Address().Hex())
+	return nil
+}
+
+// encryptMnemonic asks for the passphrase and replaces the mnemonic of the account with the encrypted form.
+func encryptMnemonic(a types.Account, light bool) (types.Account, error) {
+	pwd, err := config.ReadPassword("Passphrase", true)
+	if err != nil {
+		return a, err
+	}
+	scryptN, scryptP := scryptParams(light)
+	return config.EncryptMnemonic(a, pwd, scryptN, scryptP)
+}
+
+func addExternalAccount(f *config.AccountRepo, name string, address string, url string) error {
+	exists, err := f.AccountExists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.Errorf("Account %s already exists", name)
+	}
+	if !common.IsHexAddress(address) {
+		return errors.Errorf("%s is not a valid address", address)
+	}
+	return f.AddAccount(types.Account{
+		Name:   name,
+		Public: common.HexToAddress(address).Hex(),
+		Signer: url,
+	})
+}
+
+func deriveAccounts(f *config.AccountRepo, name string, count int) error {
+	a, err := f.GetAccount(name)
+	if err != nil {
+		return err
+	}
+	if a.EncryptedMnemonic != "" {
+		pwd, err := config.ReadPassword("Passphrase of "+name, false)
+		if err != nil {
+			return err
+		}
+		a, err = config.UnlockMnemonic(a, pwd)
+		if err != nil {
+			return err
+		}
+	}
+	for i := 0; i < count; i++ {
+		derived, err := config.DeriveAccount(a, i)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s %s %s\n", derived.Name, derived.Address().Hex(), derived.Path)
+	}
+	return nil
+}
+
+func importAccount(f *config.AccountRepo, file string, name string) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errors.Wrap(err, "Couldn't read keystore file "+file)
+	}
+	if name == "" {
+		name = f.GetNextName()
+	}
+	exists, err := f.AccountExists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.Errorf("Account %s already exists", name)
+	}
+	a, err := config.KeystoreAccount(name, content)
+	if err != nil {
+		return err
+	}
+
+	// make sure that the key can be used later
+	pwd, err := config.ReadPassword("Passphrase of "+file, false)
+	if err != nil {
+		return err
+	}
+	_, err = config.UnlockAccount(a, pwd)
+	if err != nil {
+		return err
+	}
+
+	err = f.AddAccount(a)
+	if err != nil {
+		return err
+	}
+	fmt.Println(name + " " + a.Address().Hex())
+	return nil
+}
+
+func exportAccount(f *config.AccountRepo, name string, file string, light bool) error {
+	a, err := f.GetAccount(name)
+	if err != nil {
+		return err
+	}
+
+	content := []byte(a.Keystore)
+	if a.Keystore == "" {
+		if a.Encrypted() {
+			pwd, err := config.ReadPassword("Passphrase of "+name, false)
+			if err != nil {
+				return err
+			}
+			a, err = config.UnlockAccount(a, pwd)
+			if err != nil {
+				return err
+			}
+		}
+		key := a.PrivateKey()
+		if key == nil {
+			return errors.Errorf("Private key of account %s is not available", name)
+		}
+		pwd, err := config.ReadPassword("Passphrase", true)
+		if err != nil {
+			return err
+		}
+		scryptN, scryptP := scryptParams(light)
+		encrypted, err := config.EncryptAccount(name, key, pwd, scryptN, scryptP)
+		if err != nil {
+			return err
+		}
+		content = []byte(encrypted.Keystore)
+	}
+
+	if file == "" {
+		fmt.Println(string(content))
+		return nil
+	}
+	return ioutil.WriteFile(file, content, 0600)
+}
+
+func scryptParams(light bool) (int, int) {
+	if light {
+		return keystore.LightScryptN, keystore.LightScryptP
+	}
+	return keystore.StandardScryptN, keystore.StandardScryptP
+}
+
 func listAccounts(f *config.AccountRepo, all bool) error {
 	accounts, err := f.ListAccounts()
 	if err != nil {
@@ -161,7 +566,9 @@ func listAccounts(f *config.AccountRepo, all bool) error {
 		if a.Name == curr.Name {
 			marker = "*"
 		}
-		if all {
+		if all && a.Encrypted() {
+			fmt.Println(marker + a.Name + " " + a.Address().Hex() + " <encrypted>")
+		} else if all {
 			fmt.Println(marker + a.Name + " " + a.Address().Hex() + " " + a.Private)
 		} else {
 			fmt.Println(marker + a.Name + " " + a.Address().Hex())
@@ -3,7 +3,10 @@ package cethacea
 import (
 	"context"
 	"fmt"
+	"github.com/elek/cethacea/pkg/chain"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
 )
 
@@ -59,6 +62,20 @@ func balance(ceth *Ceth, address string, all bool) error {
 		if err != nil {
 			return err
 		}
+		if eth, ok := c.(*chain.Eth); ok {
+			var addresses []common.Address
+			for _, a := range accounts {
+				addresses = append(addresses, a.Address())
+			}
+			balances, err := eth.Balances(ctx, addresses)
+			if err != nil {
+				return errors.Wrap(err, "Couldn't get balances")
+			}
+			for ix, a := range accounts {
+				fmt.Printf("%s %s\n", a.Name, PrintEthFromDecimal(decimal.NewFromBigInt(balances[ix], -18)))
+			}
+			return nil
+		}
 		for _, a := range accounts {
 
 			balance, err := c.Balance(ctx, a.Address())
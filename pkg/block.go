@@ -4,13 +4,16 @@ import (
 	"context"
 	"fmt"
 	"github.com/elek/cethacea/pkg/chain"
+	"github.com/elek/cethacea/pkg/types"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/fatih/color"
-	"github.com/rs/zerolog/log"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/zeebo/errs/v2"
 	"math/big"
+	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 	"time"
@@ -66,14 +69,16 @@ func init() {
 
 		blockWatchCmd := cobra.Command{
 			Use:   "watch",
-			Short: "Watch for new head blocks",
+			Short: "Watch for new head blocks (until interrupted)",
 		}
+		var pollInterval time.Duration
+		blockWatchCmd.Flags().DurationVar(&pollInterval, "poll-interval", 2*time.Second, "Polling frequency when the endpoint doesn't support subscriptions (HTTP)")
 		blockWatchCmd.RunE = func(cmd *cobra.Command, args []string) error {
 			ceth, err := NewCethContext(&Settings)
 			if err != nil {
 				return err
 			}
-			return watchBlocks(ceth)
+			return watchBlocks(ceth, pollInterval)
 		}
 		blockCmd.AddCommand(&blockWatchCmd)
 
@@ -81,8 +86,9 @@ func init() {
 	{
 
 		blockAt := cobra.Command{
-			Use:   "at",
-			Short: "Find block at a specific time",
+			Use:   "at <time>",
+			Short: "Find the first block created at (or after) a specific time",
+			Args:  cobra.ExactArgs(1),
 		}
 		blockAt.RunE = func(cmd *cobra.Command, args []string) error {
 			ceth, err := NewCethContext(&Settings)
@@ -99,66 +105,103 @@ func init() {
 
 func findBlockAt(ceth *Ceth, s string) error {
 	ctx := context.Background()
-
-	point := time.Now().Add(-356 * 24 * time.Hour)
-
-	client, err := ceth.GetChainClient()
-	ethClient := client.(*chain.Eth)
+	at, err := parseTime(s)
 	if err != nil {
 		return err
 	}
-
-	first := big.NewInt(0)
-	lastBlock, err := ethClient.Client.BlockByNumber(ctx, nil)
+	client, err := ceth.GetClient()
 	if err != nil {
 		return err
 	}
+	number, err := chain.BlockAtTime(ctx, client.Client, at)
+	if err != nil {
+		return err
+	}
+	fmt.Println(number)
+	return nil
+}
 
-	last := lastBlock.Number()
-
-	for {
-		diff := new(big.Int).Sub(last, first).Int64()
-		if diff == int64(1) {
-			break
-		}
-		log.Debug().Int64("diff", diff).Msg("binary search for block")
-		middle := new(big.Int).Div(new(big.Int).Add(last, first), big.NewInt(2))
-		middleBlock, err := ethClient.Client.BlockByNumber(ctx, middle)
+// parseTime parses absolute time (RFC3339, date with or without time in local timezone, or unix time with @ prefix).
+func parseTime(s string) (time.Time, error) {
+	if strings.HasPrefix(s, "@") {
+		seconds, err := strconv.ParseInt(s[1:], 10, 64)
 		if err != nil {
-			return err
-		}
-		if middleBlock.ReceivedAt.Before(point) {
-			first = middleBlock.Number()
-		} else {
-			last = middleBlock.Number()
+			return time.Time{}, errs.Errorf("Invalid unix time %s", s)
 		}
+		return time.Unix(seconds, 0), nil
 	}
-	selected, err := ethClient.Client.BlockByNumber(ctx, last)
-	if err != nil {
-		return err
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
 	}
-	fmt.Println(selected.Number())
-	return nil
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errs.Errorf("Couldn't parse time %s (use RFC3339, YYYY-MM-DD[THH:MM:SS] or @<unix time>)", s)
 }
 
-func watchBlocks(ceth *Ceth) error {
+func watchBlocks(ceth *Ceth, pollInterval time.Duration) error {
 	client, err := ceth.GetClient()
 	if err != nil {
 		return err
 	}
-	ctx := context.Background()
-	ch := make(chan *types.Header)
-	subscription, err := client.Client.SubscribeNewHead(ctx, ch)
-	if err != nil {
-		return err
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	watcher := chain.NewHeadWatcher(client.Client)
+	watcher.PollInterval = pollInterval
+	events := make(chan chain.HeadEvent)
+	done := make(chan error, 1)
+	go func() {
+		done <- watcher.Watch(ctx, events)
+	}()
+	for {
+		select {
+		case e := <-events:
+			err = printHead(ctx, ceth, client, e)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+		case err := <-done:
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		}
 	}
+}
 
-	select {
-	case head := <-ch:
-		fmt.Println(head.Number)
-	case <-subscription.Err():
-		break
+func printHead(ctx context.Context, ceth *Ceth, client *chain.Eth, e chain.HeadEvent) error {
+	if len(e.Removed) > 0 {
+		if ceth.Settings.Format == "json" {
+			var removed []string
+			for _, h := range e.Removed {
+				removed = append(removed, h.Hex())
+			}
+			item := types.Item{}
+			item.AddField("reorg", true)
+			item.AddField("removed", removed)
+			item.AddField("ancestor", e.Header.ParentHash.Hex())
+			err := PrintJSONLine(item)
+			if err != nil {
+				return err
+			}
+		} else {
+			fmt.Println(color.YellowString("reorg: %d block(s) removed, new branch starts after %s", len(e.Removed), e.Header.ParentHash.Hex()))
+		}
 	}
+	block, err := client.BlockByHash(ctx, e.Header.Hash())
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	if ceth.Settings.Format == "json" {
+		return PrintJSONLine(blockItem(block))
+	}
+	fmt.Println(blockLine(block))
 	return nil
 }
 
@@ -182,48 +225,79 @@ func listBlocks(ceth *Ceth, limit uint64, from string) error {
 		lastBlock = uint64(val)
 	}
 
+	var numbers []*big.Int
 	for b := lastBlock; b+limit > lastBlock && b > 0; b-- {
-		block, err := client.Client.BlockByNumber(ctx, big.NewInt(int64(b)))
-		if err != nil {
-			return errs.Wrap(err)
-		}
-		gasString := fmt.Sprintf("%10d", block.GasUsed())
-		if block.GasUsed() > 15_000_000 {
-			gasString = color.RedString("%10d", block.GasUsed())
-		}
+		numbers = append(numbers, big.NewInt(int64(b)))
+	}
+	blocks, err := client.BlocksByNumber(ctx, numbers)
+	if err != nil {
+		return errs.Wrap(err)
+	}
 
-		var maxTip, minTip, sumTip *big.Int
-		sumTip = big.NewInt(0)
-		for _, tx := range block.Transactions() {
-			tip := tx.GasTipCap()
-			if maxTip == nil || tip.Cmp(maxTip) > 0 {
-				maxTip = tip
-			}
-			if minTip == nil || tip.Cmp(minTip) < 0 {
-				minTip = tip
-			}
-			sumTip = sumTip.Add(sumTip, tip)
+	for _, block := range blocks {
+		fmt.Println(blockLine(block))
+	}
+	return nil
+
+}
 
+// blockSummary calculates the gas tip statistics of the block transactions.
+func blockSummary(block *ethtypes.Block) (minTip *big.Int, average *big.Int, maxTip *big.Int) {
+	sumTip := big.NewInt(0)
+	for _, tx := range block.Transactions() {
+		tip := tx.GasTipCap()
+		if maxTip == nil || tip.Cmp(maxTip) > 0 {
+			maxTip = tip
 		}
-		blockTime := time.Unix(int64(block.Time()), 0)
-		average := big.NewInt(0)
-		if len(block.Transactions()) > 0 {
-			average = new(big.Int).Div(sumTip, big.NewInt(int64(len(block.Transactions()))))
+		if minTip == nil || tip.Cmp(minTip) < 0 {
+			minTip = tip
 		}
-		fmt.Printf("%d %10s %s %s %18s %3d %s/%s/%s\n",
-			block.Number(),
-			blockTime.Format("2006-01-02T15:04:05"),
-			block.Hash().String(),
-			gasString,
-			PrintGWei(block.BaseFee()),
-			len(block.Transactions()),
-			PrintGWei(minTip),
-			PrintGWei(average),
-			PrintGWei(maxTip),
-		)
+		sumTip = sumTip.Add(sumTip, tip)
+
 	}
-	return nil
+	average = big.NewInt(0)
+	if len(block.Transactions()) > 0 {
+		average = new(big.Int).Div(sumTip, big.NewInt(int64(len(block.Transactions()))))
+	}
+	return minTip, average, maxTip
+}
+
+// blockLine is the one line summary of the block used by list and watch.
+func blockLine(block *ethtypes.Block) string {
+	gasString := fmt.Sprintf("%10d", block.GasUsed())
+	if block.GasUsed() > 15_000_000 {
+		gasString = color.RedString("%10d", block.GasUsed())
+	}
+	minTip, average, maxTip := blockSummary(block)
+	blockTime := time.Unix(int64(block.Time()), 0)
+	return fmt.Sprintf("%d %10s %s %s %18s %3d %s/%s/%s",
+		block.Number(),
+		blockTime.Format("2006-01-02T15:04:05"),
+		block.Hash().String(),
+		gasString,
+		PrintGWei(block.BaseFee()),
+		len(block.Transactions()),
+		PrintGWei(minTip),
+		PrintGWei(average),
+		PrintGWei(maxTip),
+	)
+}
 
+// blockItem has the same data as blockLine (for structured output).
+func blockItem(block *ethtypes.Block) types.Item {
+	minTip, average, maxTip := blockSummary(block)
+	item := types.Item{}
+	item.AddField("number", block.NumberU64())
+	item.AddField("time", time.Unix(int64(block.Time()), 0).UTC().Format(time.RFC3339))
+	item.AddField("hash", block.Hash().Hex())
+	item.AddField("parent", block.ParentHash().Hex())
+	item.AddField("gasUsed", block.GasUsed())
+	item.AddField("baseFee", block.BaseFee())
+	item.AddField("transactions", len(block.Transactions()))
+	item.AddField("minTip", minTip)
+	item.AddField("avgTip", average)
+	item.AddField("maxTip", maxTip)
+	return item
 }
 
 func showBlock(ceth *Ceth, hashOrNumber string) (err error) {
@@ -231,7 +305,7 @@ func showBlock(ceth *Ceth, hashOrNumber string) (err error) {
 	if err != nil {
 		return err
 	}
-	var block *types.Block
+	var block *ethtypes.Block
 	ctx := context.Background()
 	if hashOrNumber == "" {
 		block, err = client.Client.BlockByNumber(ctx, nil)
@@ -239,7 +313,7 @@ func showBlock(ceth *Ceth, hashOrNumber string) (err error) {
 			return err
 		}
 	} else if strings.HasPrefix(hashOrNumber, "0x") {
-		block, err = client.Client.BlockByHash(ctx, common.HexToHash(hashOrNumber))
+		block, err = client.BlockByHash(ctx, common.HexToHash(hashOrNumber))
 		if err != nil {
 			return err
 		}
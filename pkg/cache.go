@@ -0,0 +1,88 @@
+package cethacea
+
+import (
+	"fmt"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/spf13/cobra"
+	"strconv"
+)
+
+func init() {
+	cacheCmd := cobra.Command{
+		Use:   "cache",
+		Short: "Manage the local cache of immutable chain data",
+	}
+	{
+		cmd := cobra.Command{
+			Use:   "stats",
+			Short: "Show the number and size of the cached entries per chain",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				ceth, err := NewCethContext(&Settings)
+				if err != nil {
+					return err
+				}
+				return cacheStats(ceth)
+			},
+		}
+		cacheCmd.AddCommand(&cmd)
+	}
+	{
+		cmd := cobra.Command{
+			Use:   "clear [<chain id>]",
+			Short: "Remove the cached data of one chain (or all the chains)",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				ceth, err := NewCethContext(&Settings)
+				if err != nil {
+					return err
+				}
+				chainID := int64(0)
+				if len(args) > 0 {
+					chainID, err = strconv.ParseInt(args[0], 10, 64)
+					if err != nil {
+						return err
+					}
+				}
+				return cacheClear(ceth, chainID)
+			},
+		}
+		cacheCmd.AddCommand(&cmd)
+	}
+	RootCmd.AddCommand(&cacheCmd)
+}
+
+func cacheStats(ceth *Ceth) error {
+	cache, err := ceth.GetCache()
+	if err != nil {
+		return err
+	}
+	stats, err := cache.Stats()
+	if err != nil {
+		return err
+	}
+	if len(stats) == 0 {
+		fmt.Println("Cache is empty (" + cache.Dir + ")")
+		return nil
+	}
+	for _, s := range stats {
+		item := types.Item{}
+		item.AddField("chainID", s.ChainID)
+		item.AddField("kind", s.Kind)
+		item.AddField("entries", s.Entries)
+		item.AddField("size", s.Size)
+		err = PrintItem(item, ceth.Settings.Format)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cacheClear(ceth *Ceth, chainID int64) error {
+	cache, err := ceth.GetCache()
+	if err != nil {
+		return err
+	}
+	return cache.Clear(chainID)
+}
@@ -6,14 +6,16 @@ import (
 	"fmt"
 	"github.com/elek/cethacea/pkg/chain"
 	"github.com/elek/cethacea/pkg/config"
+	"github.com/elek/cethacea/pkg/encoding"
 	"github.com/elek/cethacea/pkg/types"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
 	"math/big"
-	"strconv"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 type CethSettings struct {
@@ -25,8 +27,16 @@ type CethSettings struct {
 	All       bool
 	Debug     bool
 	Confirm   bool
+	NoCache   bool
 	GasTipCap string
 	Gas       uint64
+	// FeeStrategy, MaxFee and MaxCost are the fee settings of the dynamic fee transactions (see chain.FeeSettings).
+	FeeStrategy string
+	MaxFee      string
+	MaxCost     string
+	// WaitConfirmations and Timeout are used to wait for the sent transactions.
+	WaitConfirmations uint64
+	Timeout           time.Duration
 }
 
 type Ceth struct {
@@ -44,15 +54,7 @@ func (c *Ceth) GetClient() (*chain.Eth, error) {
 	if cfg.Protocol != "" && cfg.Protocol != "eth" {
 		return nil, fmt.Errorf("this opreation is not supported with protocol %s", cfg.Protocol)
 	}
-	var cap *big.Int
-	if c.Settings.GasTipCap != "" {
-		u, err := strconv.Atoi(c.Settings.GasTipCap)
-		if err != nil {
-			return nil, err
-		}
-		cap = big.NewInt(int64(u))
-	}
-	return chain.NewEth(cfg.RPCURL, c.Settings.Confirm, c.Settings.Gas, cap)
+	return c.newEth(cfg)
 }
 
 func (c *Ceth) GetChainClient() (chain.ChainClient, error) {
@@ -66,62 +68,127 @@ func (c *Ceth) GetChainClient() (chain.ChainClient, error) {
 
 	switch cfg.Protocol {
 	case "eth":
-		var cap *big.Int
-		if c.Settings.GasTipCap != "" {
-			u, err := strconv.Atoi(c.Settings.GasTipCap)
-			if err != nil {
-				return nil, err
-			}
-			cap = big.NewInt(int64(u))
-		}
-		return chain.NewEth(cfg.RPCURL, c.Settings.Confirm, c.Settings.Gas, cap)
+		return c.newEth(cfg)
 	case "zksync2":
-		return chain.NewZksync2(cfg.RPCURL, c.Settings.Confirm)
+		return chain.NewZksync2(cfg, c.Settings.Confirm)
 	default:
 		return nil, fmt.Errorf("unsupported protocol %s", cfg.Protocol)
 	}
 }
 
+func (c *Ceth) newEth(cfg types.ChainConfig) (*chain.Eth, error) {
+	fees, err := c.GetFeeSettings()
+	if err != nil {
+		return nil, err
+	}
+	client, err := chain.NewEth(cfg, c.Settings.Confirm, c.Settings.Gas, fees)
+	if err != nil {
+		return nil, err
+	}
+	if !c.Settings.NoCache {
+		client.Cache, err = c.GetCache()
+		if err != nil {
+			return nil, err
+		}
+	}
+	client.Nonces, err = c.GetNonceManager()
+	if err != nil {
+		return nil, err
+	}
+	client.WaitConfirmations = c.Settings.WaitConfirmations
+	client.WaitTimeout = c.Settings.Timeout
+	return client, nil
+}
+
+// GetFeeSettings parses the fee related flags (amounts can be defined with wei, gwei or ether unit).
+func (c *Ceth) GetFeeSettings() (chain.FeeSettings, error) {
+	strategy, err := chain.ParseFeeStrategy(c.Settings.FeeStrategy)
+	if err != nil {
+		return chain.FeeSettings{}, err
+	}
+	fees := chain.FeeSettings{
+		Strategy: strategy,
+	}
+	fees.GasTipCap, err = optionalAmount(c.Settings.GasTipCap)
+	if err != nil {
+		return chain.FeeSettings{}, err
+	}
+	fees.GasFeeCap, err = optionalAmount(c.Settings.MaxFee)
+	if err != nil {
+		return chain.FeeSettings{}, err
+	}
+	fees.MaxCost, err = optionalAmount(c.Settings.MaxCost)
+	if err != nil {
+		return chain.FeeSettings{}, err
+	}
+	return fees, fees.Validate()
+}
+
+func optionalAmount(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	return encoding.ParseAmount(value)
+}
+
+// GetNonceManager returns the local nonce reservations of the accounts.
+func (c *Ceth) GetNonceManager() (*chain.NonceManager, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return nil, err
+	}
+	return chain.NewNonceManager(filepath.Join(dir, "nonces")), nil
+}
+
+// GetCache returns the local cache of the immutable chain data.
+func (c *Ceth) GetCache() (*chain.Cache, error) {
+	dir, err := config.CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return chain.NewCache(dir), nil
+}
+
 func (c *Ceth) GetRpcClient(ctx context.Context) (*rpc.Client, error) {
-	chain, err := c.ChainManager.GetCurrentChain()
+	cfg, err := c.ChainManager.GetCurrentChain()
 	if err != nil {
 		return nil, err
 	}
 
-	rpcClient, err := rpc.DialContext(ctx, chain.RPCURL)
+	rpcClient, err := chain.DialChain(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 	return rpcClient, nil
 }
 
-func (c *Ceth) AccountContractClient() (types.Account, types.Contract, *chain.Eth, error) {
-	account, err := c.AccountRepo.GetCurrentAccount()
+func (c *Ceth) SignerContractClient() (types.Signer, types.Contract, *chain.Eth, error) {
+	signer, err := c.AccountRepo.GetCurrentSigner()
 	if err != nil {
-		return types.Account{}, types.Contract{}, nil, err
+		return nil, types.Contract{}, nil, err
 	}
 	contract, err := c.GetCurrentContract()
 	if err != nil {
-		return types.Account{}, types.Contract{}, nil, err
+		return nil, types.Contract{}, nil, err
 	}
 	client, err := c.GetClient()
 	if err != nil {
-		return types.Account{}, types.Contract{}, nil, err
+		return nil, types.Contract{}, nil, err
 	}
-	return account, contract, client, nil
+	return signer, contract, client, nil
 }
 
-func (c *Ceth) AccountClient() (types.Account, *chain.Eth, error) {
-	account, err := c.AccountRepo.GetCurrentAccount()
+func (c *Ceth) SignerClient() (types.Signer, *chain.Eth, error) {
+	signer, err := c.AccountRepo.GetCurrentSigner()
 	if err != nil {
-		return types.Account{}, nil, err
+		return nil, nil, err
 	}
 
 	client, err := c.GetClient()
 	if err != nil {
-		return types.Account{}, nil, err
+		return nil, nil, err
 	}
-	return account, client, nil
+	return signer, client, nil
 }
 
 func NewCethContext(settings *CethSettings) (*Ceth, error) {
@@ -186,6 +253,10 @@ func (c *Ceth) GetCurrentAccount() (types.Account, error) {
 	return c.AccountRepo.GetCurrentAccount()
 }
 
+func (c *Ceth) GetCurrentSigner() (types.Signer, error) {
+	return c.AccountRepo.GetCurrentSigner()
+}
+
 func (c *Ceth) getCurrentChainID() (int64, error) {
 	chainCfg, err := c.ChainManager.GetCurrentChain()
 	if err != nil {
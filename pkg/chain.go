@@ -3,12 +3,14 @@ package cethacea
 import (
 	"context"
 	"fmt"
+	"github.com/elek/cethacea/pkg/chain"
 	"github.com/elek/cethacea/pkg/config"
 	"github.com/elek/cethacea/pkg/types"
 	"github.com/ktr0731/go-fuzzyfinder"
 	"github.com/spf13/cobra"
 	"regexp"
 	"strings"
+	"time"
 )
 
 func init() {
@@ -20,15 +22,18 @@ func init() {
 	}
 
 	addCmd := cobra.Command{
-		Use:  "add <name> <url>",
-		Args: cobra.ExactArgs(2),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			ceth, err := NewCethContext(&Settings)
-			if err != nil {
-				return err
-			}
-			return addChain(ceth, args[0], args[1])
-		},
+		Use:   "add <name> <url> [<url>...]",
+		Short: "Add new chain with one or more RPC endpoints",
+		Args:  cobra.MinimumNArgs(2),
+	}
+	policy := addCmd.Flags().String("policy", "", "Endpoint selection policy if more than one URL is used (failover, round-robin, fastest-head)")
+	txType := addCmd.Flags().String("tx-type", "", "Preferred type of the sent transactions (legacy, eip2930, eip1559; default: eip1559)")
+	addCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ceth, err := NewCethContext(&Settings)
+		if err != nil {
+			return err
+		}
+		return addChain(ceth, args[0], args[1:], *policy, *txType)
 	}
 	listCmd := cobra.Command{
 		Use:     "list",
@@ -118,7 +123,29 @@ func info(ceth *Ceth) error {
 	if err != nil {
 		return err
 	}
-	return PrintItem(info, ceth.Settings.Format)
+	err = PrintItem(info, ceth.Settings.Format)
+	if err != nil {
+		return err
+	}
+	cfg, err := ceth.ChainManager.GetCurrentChain()
+	if err != nil {
+		return err
+	}
+	for _, s := range chain.CheckEndpoints(context.Background(), cfg.Endpoints(), cfg.ChainID) {
+		item := types.Item{}
+		item.AddField("endpoint", maskSecret(s.URL))
+		item.AddField("status", s.Status)
+		if s.Err == nil {
+			item.AddField("chainID", s.ChainID)
+			item.AddField("head", s.Head)
+			item.AddField("latency", s.Latency.Round(time.Millisecond).String())
+		}
+		err = PrintItem(item, ceth.Settings.Format)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func id(ceth *Ceth) error {
@@ -147,7 +174,7 @@ func switchChain(ceth *Ceth, s string) error {
 		}
 
 		idx, _ := fuzzyfinder.Find(chains, func(i int) string {
-			return fmt.Sprintf("[%s] %s", chains[i].Name, chains[i].RPCURL)
+			return fmt.Sprintf("[%s] %s", chains[i].Name, maskUrl(chains[i]))
 		})
 		err = ceth.SetDefaultChain(chains[idx].Name)
 		if err != nil {
@@ -184,7 +211,18 @@ func listChain(ceth *Ceth) error {
 }
 
 func maskUrl(c types.ChainConfig) string {
-	url := c.RPCURL
+	var urls []string
+	for _, u := range c.Endpoints() {
+		urls = append(urls, maskSecret(u))
+	}
+	res := strings.Join(urls, ",")
+	if len(urls) > 1 && c.Policy != "" {
+		res += " (" + c.Policy + ")"
+	}
+	return res
+}
+
+func maskSecret(url string) string {
 	if strings.Contains(url, "infura.io") {
 		re, _ := regexp.Compile("/v3/[0-9a-zA-Z]+")
 		url = re.ReplaceAllString(url, "/v3/*********")
@@ -192,9 +230,20 @@ func maskUrl(c types.ChainConfig) string {
 	return url
 }
 
-func addChain(ceth *Ceth, name string, url string) error {
+func addChain(ceth *Ceth, name string, urls []string, policy string, txType string) error {
+	switch policy {
+	case "", chain.PolicyFailover, chain.PolicyRoundRobin, chain.PolicyFastestHead:
+	default:
+		return fmt.Errorf("unknown endpoint policy %s", policy)
+	}
+	if _, err := chain.ParseTxType(txType); err != nil {
+		return err
+	}
 	return ceth.ChainManager.AddChain(types.ChainConfig{
 		Name:   name,
-		RPCURL: url,
+		RPCURL: urls[0],
+		URLs:   urls[1:],
+		Policy: policy,
+		TxType: txType,
 	})
 }
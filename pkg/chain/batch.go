@@ -0,0 +1,152 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+	"math/big"
+)
+
+// batchCall sends the requests with JSON-RPC batches (at most DefaultBatchSize requests per batch) and returns the
+// first error (either transport or request error).
+func batchCall(ctx context.Context, batch BatchCaller, elems []rpc.BatchElem) error {
+	for start := 0; start < len(elems); start += DefaultBatchSize {
+		end := start + DefaultBatchSize
+		if end > len(elems) {
+			end = len(elems)
+		}
+		err := batch.BatchCallContext(ctx, elems[start:end])
+		if err != nil {
+			return errors.Wrap(err, "JSON-RPC batch request is failed")
+		}
+	}
+	for _, e := range elems {
+		if e.Error != nil {
+			return errors.Wrapf(e.Error, "%s is failed", e.Method)
+		}
+	}
+	return nil
+}
+
+// BlocksByNumber downloads the blocks (with transactions) with batched requests.
+func BlocksByNumber(ctx context.Context, batch BatchCaller, numbers []*big.Int) ([]*ethtypes.Block, error) {
+	elems := make([]rpc.BatchElem, len(numbers))
+	responses := make([]json.RawMessage, len(numbers))
+	for ix, n := range numbers {
+		elems[ix] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{hexutil.EncodeBig(n), true},
+			Result: &responses[ix],
+		}
+	}
+	err := batchCall(ctx, batch, elems)
+	if err != nil {
+		return nil, err
+	}
+	var blocks []*ethtypes.Block
+	for ix, raw := range responses {
+		block, err := decodeBlock(raw)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Couldn't decode block %s", numbers[ix])
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func decodeBlock(raw json.RawMessage) (*ethtypes.Block, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, ethereum.NotFound
+	}
+	var header ethtypes.Header
+	err := json.Unmarshal(raw, &header)
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Transactions []*ethtypes.Transaction `json:"transactions"`
+	}
+	err = json.Unmarshal(raw, &body)
+	if err != nil {
+		return nil, err
+	}
+	return ethtypes.NewBlockWithHeader(&header).WithBody(body.Transactions, nil), nil
+}
+
+// TransactionAndReceipt returns the transaction and the receipt with one batch request. Receipt is nil for pending
+// transactions.
+func TransactionAndReceipt(ctx context.Context, batch BatchCaller, hash common.Hash) (*ethtypes.Transaction, *ethtypes.Receipt, error) {
+	return transactionAndReceipt(ctx, batch, hash, nil)
+}
+
+// transactionAndReceipt requests the transaction and the receipt (and the current block number, if head is not nil) in
+// one batch.
+func transactionAndReceipt(ctx context.Context, batch BatchCaller, hash common.Hash, head *hexutil.Uint64) (*ethtypes.Transaction, *ethtypes.Receipt, error) {
+	var rawTx, rawReceipt json.RawMessage
+	elems := []rpc.BatchElem{
+		{
+			Method: "eth_getTransactionByHash",
+			Args:   []interface{}{hash},
+			Result: &rawTx,
+		},
+		{
+			Method: "eth_getTransactionReceipt",
+			Args:   []interface{}{hash},
+			Result: &rawReceipt,
+		},
+	}
+	if head != nil {
+		elems = append(elems, rpc.BatchElem{
+			Method: "eth_blockNumber",
+			Result: head,
+		})
+	}
+	err := batchCall(ctx, batch, elems)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rawTx) == 0 || string(rawTx) == "null" {
+		return nil, nil, ethereum.NotFound
+	}
+	tx := &ethtypes.Transaction{}
+	err = json.Unmarshal(rawTx, tx)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Couldn't decode transaction")
+	}
+	if len(rawReceipt) == 0 || string(rawReceipt) == "null" {
+		return tx, nil, nil
+	}
+	receipt := &ethtypes.Receipt{}
+	err = json.Unmarshal(rawReceipt, receipt)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Couldn't decode receipt")
+	}
+	return tx, receipt, nil
+}
+
+// Balances returns the balance of all the accounts with batched requests.
+func Balances(ctx context.Context, batch BatchCaller, accounts []common.Address) ([]*big.Int, error) {
+	elems := make([]rpc.BatchElem, len(accounts))
+	responses := make([]hexutil.Big, len(accounts))
+	for ix, a := range accounts {
+		elems[ix] = rpc.BatchElem{
+			Method: "eth_getBalance",
+			Args:   []interface{}{a, "latest"},
+			Result: &responses[ix],
+		}
+	}
+	err := batchCall(ctx, batch, elems)
+	if err != nil {
+		return nil, err
+	}
+	var res []*big.Int
+	for ix := range responses {
+		res = append(res, responses[ix].ToInt())
+	}
+	return res, nil
+}
@@ -0,0 +1,107 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+)
+
+// nodeStub serves a few eth_ methods from memory.
+type nodeStub struct {
+	tx *ethtypes.Transaction
+}
+
+func (s *nodeStub) GetBalance(address common.Address, block string) *hexutil.Big {
+	return (*hexutil.Big)(new(big.Int).SetBytes(address.Bytes()[19:]))
+}
+
+func (s *nodeStub) GetBlockByNumber(number hexutil.Big, full bool) (map[string]interface{}, error) {
+	header := &ethtypes.Header{
+		Number:     number.ToInt(),
+		Difficulty: big.NewInt(0),
+		GasUsed:    21000,
+		Time:       1000 + number.ToInt().Uint64(),
+		BaseFee:    big.NewInt(7),
+	}
+	raw, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]interface{}{}
+	err = json.Unmarshal(raw, &res)
+	if err != nil {
+		return nil, err
+	}
+	res["transactions"] = []*ethtypes.Transaction{s.tx}
+	return res, nil
+}
+
+func (s *nodeStub) GetTransactionByHash(hash common.Hash) *ethtypes.Transaction {
+	if hash == s.tx.Hash() {
+		return s.tx
+	}
+	return nil
+}
+
+func (s *nodeStub) GetTransactionReceipt(hash common.Hash) *ethtypes.Receipt {
+	return nil
+}
+
+func TestBatchTransport(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	to := common.HexToAddress("0x01")
+	tx, err := ethtypes.SignNewTx(key, ethtypes.NewLondonSigner(big.NewInt(5)), &ethtypes.DynamicFeeTx{
+		ChainID:   big.NewInt(5),
+		GasTipCap: big.NewInt(3),
+		GasFeeCap: big.NewInt(10),
+		Gas:       21000,
+		To:        &to,
+	})
+	require.NoError(t, err)
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	require.NoError(t, server.RegisterName("eth", &nodeStub{tx: tx}))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	RequestMetrics = NewMetrics()
+	client, err := DialRPC(context.Background(), httpServer.URL)
+	require.NoError(t, err)
+	defer client.Close()
+	ctx := context.Background()
+
+	balances, err := Balances(ctx, client, []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02")})
+	require.NoError(t, err)
+	require.Equal(t, []*big.Int{big.NewInt(1), big.NewInt(2)}, balances)
+
+	blocks, err := BlocksByNumber(ctx, client, []*big.Int{big.NewInt(10), big.NewInt(9), big.NewInt(8)})
+	require.NoError(t, err)
+	require.Len(t, blocks, 3)
+	require.Equal(t, uint64(1009), blocks[1].Time())
+	require.Equal(t, tx.Hash(), blocks[1].Transactions()[0].Hash())
+
+	pending, receipt, err := TransactionAndReceipt(ctx, client, tx.Hash())
+	require.NoError(t, err)
+	require.Equal(t, tx.Hash(), pending.Hash())
+	require.Nil(t, receipt)
+
+	require.Equal(t, 3, RequestMetrics.RoundTrips())
+	stats := map[string]MethodStat{}
+	for _, s := range RequestMetrics.Stats() {
+		stats[s.Method] = s
+	}
+	require.Equal(t, 2, stats["eth_getBalance"].Calls)
+	require.Equal(t, 1, stats["eth_getBalance"].Requests)
+	require.Equal(t, 3, stats["eth_getBlockByNumber"].Calls)
+	require.Equal(t, 1, stats["eth_getTransactionReceipt"].Requests)
+}
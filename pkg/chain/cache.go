@@ -0,0 +1,183 @@
+package chain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Cache stores immutable chain data (blocks by hash, finalized receipts, contract code, token metadata) in JSON files:
+// <dir>/<chainID>/<kind>/<key>.json. A nil Cache is valid, and doesn't store anything.
+type Cache struct {
+	Dir string
+}
+
+// CacheStat is the summary of one kind of the cached data of one chain.
+type CacheStat struct {
+	ChainID int64
+	Kind    string
+	Entries int
+	Size    int64
+}
+
+func NewCache(dir string) *Cache {
+	return &Cache{
+		Dir: dir,
+	}
+}
+
+func (c *Cache) path(chainID int64, kind string, key string) string {
+	return filepath.Join(c.Dir, strconv.FormatInt(chainID, 10), kind, strings.ToLower(key)+".json")
+}
+
+// Get reads the cached value to the value pointer. Returns false if it's not cached (or not readable).
+func (c *Cache) Get(chainID int64, kind string, key string, value interface{}) bool {
+	if c == nil || chainID == 0 {
+		return false
+	}
+	content, err := ioutil.ReadFile(c.path(chainID, kind, key))
+	if err != nil {
+		return false
+	}
+	err = json.Unmarshal(content, value)
+	if err != nil {
+		log.Debug().Err(err).Str("kind", kind).Str("key", key).Msg("Invalid cache entry")
+		return false
+	}
+	return true
+}
+
+// Put saves the value to the cache. Errors are logged only, as caching is optional.
+func (c *Cache) Put(chainID int64, kind string, key string, value interface{}) {
+	if c == nil || chainID == 0 {
+		return
+	}
+	err := c.put(c.path(chainID, kind, key), value)
+	if err != nil {
+		log.Debug().Err(err).Str("kind", kind).Str("key", key).Msg("Couldn't save cache entry")
+	}
+}
+
+func (c *Cache) put(file string, value interface{}) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(file), 0700)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(content)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// EndpointChainID returns the cached chain ID of the RPC endpoint.
+func (c *Cache) EndpointChainID(url string) (int64, bool) {
+	if c == nil {
+		return 0, false
+	}
+	var chainID int64
+	content, err := ioutil.ReadFile(c.endpointPath(url))
+	if err != nil {
+		return 0, false
+	}
+	if json.Unmarshal(content, &chainID) != nil || chainID == 0 {
+		return 0, false
+	}
+	return chainID, true
+}
+
+// SetEndpointChainID saves the chain ID of the RPC endpoint.
+func (c *Cache) SetEndpointChainID(url string, chainID int64) {
+	if c == nil {
+		return
+	}
+	err := c.put(c.endpointPath(url), chainID)
+	if err != nil {
+		log.Debug().Err(err).Msg("Couldn't save chain ID of the endpoint")
+	}
+}
+
+func (c *Cache) endpointPath(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return filepath.Join(c.Dir, "endpoints", hex.EncodeToString(hash[:8])+".json")
+}
+
+// Stats returns the number and size of the cached entries per chain and kind.
+func (c *Cache) Stats() ([]CacheStat, error) {
+	stats := map[string]*CacheStat{}
+	err := filepath.Walk(c.Dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(file, ".json") {
+			return nil
+		}
+		rel, err := filepath.Rel(c.Dir, file)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(rel, string(filepath.Separator))
+		if len(parts) != 3 {
+			return nil
+		}
+		chainID, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil
+		}
+		id := parts[0] + "/" + parts[1]
+		stat, found := stats[id]
+		if !found {
+			stat = &CacheStat{ChainID: chainID, Kind: parts[1]}
+			stats[id] = stat
+		}
+		stat.Entries++
+		stat.Size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Couldn't read cache directory "+c.Dir)
+	}
+	var res []CacheStat
+	for _, s := range stats {
+		res = append(res, *s)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].ChainID != res[j].ChainID {
+			return res[i].ChainID < res[j].ChainID
+		}
+		return res[i].Kind < res[j].Kind
+	})
+	return res, nil
+}
+
+// Clear removes the cached data of one chain (or all the chains if chainID is 0).
+func (c *Cache) Clear(chainID int64) error {
+	dir := c.Dir
+	if chainID != 0 {
+		dir = filepath.Join(c.Dir, strconv.FormatInt(chainID, 10))
+	}
+	return os.RemoveAll(dir)
+}
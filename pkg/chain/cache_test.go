@@ -0,0 +1,120 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+)
+
+// finalizedStub serves one transaction which is included in block 10, while the head is at block 100.
+type finalizedStub struct {
+	tx *ethtypes.Transaction
+}
+
+func (s *finalizedStub) ChainId() hexutil.Uint64 {
+	return 5
+}
+
+func (s *finalizedStub) BlockNumber() hexutil.Uint64 {
+	return 100
+}
+
+func (s *finalizedStub) GetTransactionByHash(hash common.Hash) *ethtypes.Transaction {
+	return s.tx
+}
+
+func (s *finalizedStub) GetTransactionReceipt(hash common.Hash) *ethtypes.Receipt {
+	return &ethtypes.Receipt{
+		Status:      ethtypes.ReceiptStatusSuccessful,
+		TxHash:      hash,
+		GasUsed:     21000,
+		Logs:        []*ethtypes.Log{},
+		BlockHash:   common.HexToHash("0x10"),
+		BlockNumber: big.NewInt(10),
+	}
+}
+
+func (s *finalizedStub) GetBlockByHash(hash common.Hash, full bool) (map[string]interface{}, error) {
+	raw, err := json.Marshal(&ethtypes.Header{
+		Number:     big.NewInt(10),
+		Difficulty: big.NewInt(0),
+		BaseFee:    big.NewInt(7),
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]interface{}{}
+	return res, json.Unmarshal(raw, &res)
+}
+
+func TestCache(t *testing.T) {
+	cache := NewCache(t.TempDir())
+	var info TokenInfo
+	require.False(t, cache.Get(5, "token", "0x01", &info))
+	cache.Put(5, "token", "0x01", TokenInfo{Symbol: "TST", Decimal: 18})
+	cache.Put(5, "code", "0x02", hexutil.Bytes{1, 2})
+	cache.Put(1, "code", "0x02", hexutil.Bytes{1, 2})
+	require.True(t, cache.Get(5, "token", "0x01", &info))
+	require.Equal(t, "TST", info.Symbol)
+
+	stats, err := cache.Stats()
+	require.NoError(t, err)
+	require.Len(t, stats, 3)
+	require.Equal(t, int64(1), stats[0].ChainID)
+	require.Equal(t, "token", stats[2].Kind)
+	require.Equal(t, 1, stats[2].Entries)
+
+	require.NoError(t, cache.Clear(5))
+	require.False(t, cache.Get(5, "token", "0x01", &info))
+	stats, err = cache.Stats()
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+
+	var nilCache *Cache
+	nilCache.Put(5, "token", "0x01", info)
+	require.False(t, nilCache.Get(5, "token", "0x01", &info))
+}
+
+func TestCachedTransaction(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	to := common.HexToAddress("0x01")
+	tx, err := ethtypes.SignNewTx(key, ethtypes.NewLondonSigner(big.NewInt(5)), &ethtypes.DynamicFeeTx{
+		ChainID:   big.NewInt(5),
+		GasTipCap: big.NewInt(3),
+		GasFeeCap: big.NewInt(10),
+		Gas:       21000,
+		To:        &to,
+	})
+	require.NoError(t, err)
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	require.NoError(t, server.RegisterName("eth", &finalizedStub{tx: tx}))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	RequestMetrics = NewMetrics()
+	eth, err := NewEth(types.ChainConfig{RPCURL: httpServer.URL, ChainID: 5}, false, 0, FeeSettings{})
+	require.NoError(t, err)
+	eth.Cache = NewCache(t.TempDir())
+	ctx := context.Background()
+
+	first, err := eth.GetTransaction(ctx, tx.Hash())
+	require.NoError(t, err)
+	require.Equal(t, 2, RequestMetrics.RoundTrips())
+
+	second, err := eth.GetTransaction(ctx, tx.Hash())
+	require.NoError(t, err)
+	require.Equal(t, 2, RequestMetrics.RoundTrips())
+	require.Equal(t, first.Fields, second.Fields)
+}
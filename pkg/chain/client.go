@@ -4,6 +4,8 @@ import (
 	"context"
 	"github.com/elek/cethacea/pkg/types"
 	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"math/big"
 )
@@ -13,13 +15,15 @@ type ChainClient interface {
 	TokenBalance(ctx context.Context, token common.Address, account common.Address) (*big.Int, error)
 	TokenInfo(ctx context.Context, token common.Address) (TokenInfo, error)
 
-	GetTransaction(ctx context.Context, hash common.Hash) (types.Item, error)
+	GetTransaction(ctx context.Context, hash common.Hash) (TransactionDetails, error)
 	GetChainID(ctx context.Context) (int64, error)
 	GetChainInfo(ctx context.Context) (types.Item, error)
 	GetAccountInfo(ctx context.Context, account common.Address) (types.Item, error)
 
-	SendTransaction(ctx context.Context, from types.Account, to *common.Address, options ...interface{}) (common.Hash, error)
+	SendTransaction(ctx context.Context, from types.Signer, to *common.Address, options ...interface{}) (common.Hash, error)
 	SendQuery(ctx context.Context, from common.Address, to common.Address, options ...interface{}) ([]byte, error)
+	// Waiter returns a new waiter to track the sent transactions.
+	Waiter() *TxWaiter
 }
 
 type WithData struct {
@@ -55,3 +59,28 @@ type TokenInfo struct {
 	Symbol  string
 	Decimal uint8
 }
+
+// WithTxType selects the type of the transaction (ethtypes.LegacyTxType, ethtypes.AccessListTxType or
+// ethtypes.DynamicFeeTxType) instead of the default of the chain.
+type WithTxType struct {
+	Type uint8
+}
+
+// WithAccessList sets the access list of the transaction (EIP-2930). Legacy transactions are sent as EIP-2930
+// transactions if the list is not empty.
+type WithAccessList struct {
+	List ethtypes.AccessList
+}
+
+// ParseTxType returns the transaction type of the name used in the chain configuration (legacy, eip2930 or eip1559).
+func ParseTxType(name string) (uint8, error) {
+	switch name {
+	case "legacy":
+		return ethtypes.LegacyTxType, nil
+	case "eip2930":
+		return ethtypes.AccessListTxType, nil
+	case "eip1559", "":
+		return ethtypes.DynamicFeeTxType, nil
+	}
+	return 0, errors.Errorf("Unknown transaction type %s (use legacy, eip2930 or eip1559)", name)
+}
@@ -0,0 +1,393 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// PolicyFailover uses the first working endpoint until it fails.
+	PolicyFailover = "failover"
+	// PolicyRoundRobin rotates the endpoints request by request.
+	PolicyRoundRobin = "round-robin"
+	// PolicyFastestHead prefers the endpoint with the highest block, and the lowest latency.
+	PolicyFastestHead = "fastest-head"
+)
+
+var (
+	// HeadLagThreshold is the number of blocks an endpoint can be behind the others without being reported as lagging.
+	HeadLagThreshold uint64 = 5
+
+	// HealthCheckTimeout is the timeout of the health check request of one endpoint.
+	HealthCheckTimeout = 5 * time.Second
+
+	retryDelay = 200 * time.Millisecond
+
+	// nonIdempotentMethods are not sent again to another endpoint after a failed response, as the first endpoint may
+	// have processed them (for example, the transaction may be broadcast already).
+	nonIdempotentMethods = map[string]bool{
+		"eth_sendRawTransaction":   true,
+		"eth_sendTransaction":      true,
+		"personal_sendTransaction": true,
+	}
+)
+
+// EndpointStatus is the result of the health check of one RPC endpoint.
+type EndpointStatus struct {
+	URL     string
+	ChainID int64
+	Head    uint64
+	Latency time.Duration
+	Err     error
+	// Status is a human-readable summary: ok, lagging, wrong chain or the error.
+	Status  string
+	Healthy bool
+	// tier is the rank of the status (0: ok, 1: lagging, 2: wrong chain, 3: error).
+	tier int
+}
+
+// CheckEndpoints requests the chain ID and the head block from all the endpoints. Endpoints with different chain ID
+// (compared to the expected one, or to the first responding endpoint if expected is 0) or with a head behind the others
+// are reported as unhealthy.
+func CheckEndpoints(ctx context.Context, urls []string, expectedChainID int64) []EndpointStatus {
+	res := make([]EndpointStatus, len(urls))
+	wg := sync.WaitGroup{}
+	for ix, u := range urls {
+		wg.Add(1)
+		go func(ix int, u string) {
+			defer wg.Done()
+			res[ix] = checkEndpoint(ctx, u)
+		}(ix, u)
+	}
+	wg.Wait()
+
+	var maxHead uint64
+	for _, s := range res {
+		if s.Err != nil {
+			continue
+		}
+		if expectedChainID == 0 {
+			expectedChainID = s.ChainID
+		}
+		if s.ChainID == expectedChainID && s.Head > maxHead {
+			maxHead = s.Head
+		}
+	}
+	for ix := range res {
+		s := &res[ix]
+		switch {
+		case s.Err != nil:
+			s.Status = "error: " + s.Err.Error()
+			s.tier = 3
+		case s.ChainID != expectedChainID:
+			s.Status = fmt.Sprintf("wrong chain id (expected %d)", expectedChainID)
+			s.tier = 2
+		case s.Head+HeadLagThreshold < maxHead:
+			s.Status = fmt.Sprintf("lagging %d blocks", maxHead-s.Head)
+			s.tier = 1
+		default:
+			s.Status = "ok"
+			s.Healthy = true
+		}
+	}
+	return res
+}
+
+func checkEndpoint(ctx context.Context, u string) EndpointStatus {
+	s := EndpointStatus{
+		URL: u,
+	}
+	ctx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+	defer cancel()
+	client, err := DialRPC(ctx, u)
+	if err != nil {
+		s.Err = err
+		return s
+	}
+	defer client.Close()
+	var chainID, head hexutil.Uint64
+	start := time.Now()
+	err = batchCall(ctx, client, []rpc.BatchElem{
+		{
+			Method: "eth_chainId",
+			Result: &chainID,
+		},
+		{
+			Method: "eth_blockNumber",
+			Result: &head,
+		},
+	})
+	s.Latency = time.Since(start)
+	if err != nil {
+		s.Err = err
+		return s
+	}
+	s.ChainID = int64(chainID)
+	s.Head = uint64(head)
+	return s
+}
+
+// endpointTransport is a http.RoundTripper which sends the JSON-RPC requests to one of the endpoints (selected by the
+// policy), and retries with the next endpoint on transport errors. Non-idempotent requests are retried only if the
+// connection couldn't be established.
+type endpointTransport struct {
+	endpoints []*url.URL
+	policy    string
+	next      http.RoundTripper
+
+	mu sync.Mutex
+	// current is the index of the preferred endpoint (failover) or the next endpoint (round-robin).
+	current int
+	// order is the ranking of the endpoints, used by the fastest-head policy.
+	order  []int
+	ranked sync.Once
+	rank   func() []int
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+	}
+
+	idempotent := isIdempotent(body)
+	order := t.candidates()
+	attempts := len(order)
+	if attempts < 2 {
+		attempts = 2
+	}
+	var resp *http.Response
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 && i%len(order) == 0 {
+			select {
+			case <-time.After(retryDelay):
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+		}
+		ix := order[i%len(order)]
+		resp, err = t.next.RoundTrip(withEndpoint(req, t.endpoints[ix], body))
+		if !retryable(req, resp, err, idempotent) {
+			t.succeeded(ix)
+			return resp, err
+		}
+		if resp != nil && i < attempts-1 {
+			_ = resp.Body.Close()
+		}
+		t.failed(ix)
+	}
+	return resp, err
+}
+
+// candidates returns the endpoint indexes in the order of the retries.
+func (t *endpointTransport) candidates() []int {
+	if t.policy == PolicyFastestHead && len(t.endpoints) > 1 {
+		t.ranked.Do(func() {
+			order := t.rank()
+			t.mu.Lock()
+			t.order = order
+			t.mu.Unlock()
+		})
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var res []int
+	switch t.policy {
+	case PolicyFastestHead:
+		res = append(res, t.order...)
+	default:
+		for i := range t.endpoints {
+			res = append(res, (t.current+i)%len(t.endpoints))
+		}
+		if t.policy == PolicyRoundRobin {
+			t.current = (t.current + 1) % len(t.endpoints)
+		}
+	}
+	return res
+}
+
+func (t *endpointTransport) succeeded(ix int) {
+	if t.policy != PolicyFailover {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current = ix
+}
+
+func (t *endpointTransport) failed(ix int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch t.policy {
+	case PolicyFailover:
+		if t.current == ix {
+			t.current = (ix + 1) % len(t.endpoints)
+		}
+	case PolicyFastestHead:
+		// move the failed endpoint to the end of the ranking
+		for i, o := range t.order {
+			if o == ix {
+				t.order = append(append(t.order[:i:i], t.order[i+1:]...), ix)
+				break
+			}
+		}
+	}
+}
+
+func withEndpoint(req *http.Request, endpoint *url.URL, body []byte) *http.Request {
+	r := req.Clone(req.Context())
+	r.URL = endpoint
+	r.Host = endpoint.Host
+	if body != nil {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+	}
+	return r
+}
+
+// retryable returns true if the request can be sent again to another endpoint.
+func retryable(req *http.Request, resp *http.Response, err error, idempotent bool) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if !idempotent {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// isIdempotent returns false if the JSON-RPC request (or any request of the batch) can't be sent twice safely.
+func isIdempotent(body []byte) bool {
+	type call struct {
+		Method string `json:"method"`
+	}
+	calls := []call{{}}
+	var err error
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &calls)
+	} else {
+		err = json.Unmarshal(trimmed, &calls[0])
+	}
+	if err != nil {
+		return false
+	}
+	for _, c := range calls {
+		if nonIdempotentMethods[c.Method] {
+			return false
+		}
+	}
+	return true
+}
+
+func isHTTP(u string) bool {
+	return strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://")
+}
+
+// DialChain creates the RPC client for all the endpoints of the chain. Requests of HTTP endpoints are sent according
+// to the policy of the chain and retried on transport errors. Websocket/IPC endpoints are selected only during the
+// dial.
+func DialChain(ctx context.Context, cfg types.ChainConfig) (*rpc.Client, error) {
+	urls := cfg.Endpoints()
+	if len(urls) == 0 {
+		return nil, errors.Errorf("No RPC URL is configured for chain %s", cfg.Name)
+	}
+	policy := cfg.Policy
+	switch policy {
+	case "":
+		policy = PolicyFailover
+	case PolicyFailover, PolicyRoundRobin, PolicyFastestHead:
+	default:
+		return nil, errors.Errorf("Unknown endpoint policy %s (supported: %s, %s, %s)", policy, PolicyFailover, PolicyRoundRobin, PolicyFastestHead)
+	}
+
+	for _, u := range urls {
+		if !isHTTP(u) {
+			return dialFirst(ctx, urls, policy, cfg.ChainID)
+		}
+	}
+	transport := &endpointTransport{
+		policy: policy,
+		next: &meteredTransport{
+			next:    http.DefaultTransport,
+			metrics: RequestMetrics,
+		},
+		rank: func() []int {
+			return rankEndpoints(CheckEndpoints(context.Background(), urls, cfg.ChainID))
+		},
+	}
+	for _, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid RPC URL %s", u)
+		}
+		transport.endpoints = append(transport.endpoints, parsed)
+		transport.order = append(transport.order, len(transport.order))
+	}
+	return rpc.DialHTTPWithClient(urls[0], &http.Client{
+		Transport: transport,
+	})
+}
+
+// dialFirst connects to the first available endpoint.
+func dialFirst(ctx context.Context, urls []string, policy string, chainID int64) (*rpc.Client, error) {
+	order := make([]int, len(urls))
+	for ix := range order {
+		order[ix] = ix
+	}
+	if policy == PolicyFastestHead && len(urls) > 1 {
+		order = rankEndpoints(CheckEndpoints(ctx, urls, chainID))
+	}
+	var err error
+	for _, ix := range order {
+		var client *rpc.Client
+		client, err = DialRPC(ctx, urls[ix])
+		if err == nil {
+			return client, nil
+		}
+	}
+	return nil, errors.Wrap(err, "None of the RPC endpoints are available")
+}
+
+// rankEndpoints orders the endpoints: healthy ones first (then lagging, wrong chain, failed), higher head first, lower
+// latency first.
+func rankEndpoints(statuses []EndpointStatus) []int {
+	order := make([]int, len(statuses))
+	for ix := range order {
+		order[ix] = ix
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := statuses[order[i]], statuses[order[j]]
+		if a.tier != b.tier {
+			return a.tier < b.tier
+		}
+		if a.Head != b.Head {
+			return a.Head > b.Head
+		}
+		return a.Latency < b.Latency
+	})
+	return order
+}
@@ -0,0 +1,147 @@
+package chain
+
+import (
+	"context"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// headStub serves the chain ID and the head block, and counts the requests.
+type headStub struct {
+	chainID uint64
+	head    uint64
+	calls   int32
+}
+
+func (s *headStub) ChainId() hexutil.Uint64 {
+	atomic.AddInt32(&s.calls, 1)
+	return hexutil.Uint64(s.chainID)
+}
+
+func (s *headStub) BlockNumber() hexutil.Uint64 {
+	atomic.AddInt32(&s.calls, 1)
+	return hexutil.Uint64(s.head)
+}
+
+func (s *headStub) SendRawTransaction(tx hexutil.Bytes) common.Hash {
+	atomic.AddInt32(&s.calls, 1)
+	return common.Hash{1}
+}
+
+func startHeadStub(t *testing.T, chainID uint64, head uint64) (*headStub, string) {
+	stub := &headStub{chainID: chainID, head: head}
+	server := rpc.NewServer()
+	t.Cleanup(server.Stop)
+	require.NoError(t, server.RegisterName("eth", stub))
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	return stub, httpServer.URL
+}
+
+func failingEndpoint(t *testing.T) string {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(httpServer.Close)
+	return httpServer.URL
+}
+
+func blockNumber(t *testing.T, client *rpc.Client) uint64 {
+	var head hexutil.Uint64
+	require.NoError(t, client.CallContext(context.Background(), &head, "eth_blockNumber"))
+	return uint64(head)
+}
+
+func TestFailover(t *testing.T) {
+	_, url := startHeadStub(t, 5, 100)
+	client, err := DialChain(context.Background(), types.ChainConfig{
+		RPCURL: failingEndpoint(t),
+		URLs:   []string{url},
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	require.Equal(t, uint64(100), blockNumber(t, client))
+	require.Equal(t, uint64(100), blockNumber(t, client))
+}
+
+func TestFailoverNonIdempotent(t *testing.T) {
+	stub, url := startHeadStub(t, 5, 100)
+	client, err := DialChain(context.Background(), types.ChainConfig{
+		RPCURL: failingEndpoint(t),
+		URLs:   []string{url},
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	var hash common.Hash
+	// the failed endpoint may have broadcast the transaction
+	err = client.CallContext(context.Background(), &hash, "eth_sendRawTransaction", hexutil.Bytes{1})
+	require.Error(t, err)
+	require.Equal(t, int32(0), stub.calls)
+
+	// transaction is sent to the next endpoint if the connection is refused
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	client, err = DialChain(context.Background(), types.ChainConfig{
+		RPCURL: closed.URL,
+		URLs:   []string{url},
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.CallContext(context.Background(), &hash, "eth_sendRawTransaction", hexutil.Bytes{1}))
+	require.Equal(t, common.Hash{1}, hash)
+	require.Equal(t, int32(1), stub.calls)
+}
+
+func TestRoundRobin(t *testing.T) {
+	first, url1 := startHeadStub(t, 5, 100)
+	second, url2 := startHeadStub(t, 5, 100)
+	client, err := DialChain(context.Background(), types.ChainConfig{
+		RPCURL: url1,
+		URLs:   []string{url2},
+		Policy: PolicyRoundRobin,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	for i := 0; i < 4; i++ {
+		blockNumber(t, client)
+	}
+	require.Equal(t, int32(2), first.calls)
+	require.Equal(t, int32(2), second.calls)
+}
+
+func TestFastestHead(t *testing.T) {
+	_, behind := startHeadStub(t, 5, 90)
+	_, wrongChain := startHeadStub(t, 1, 200)
+	_, best := startHeadStub(t, 5, 100)
+	client, err := DialChain(context.Background(), types.ChainConfig{
+		RPCURL:  behind,
+		URLs:    []string{wrongChain, best},
+		Policy:  PolicyFastestHead,
+		ChainID: 5,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	require.Equal(t, uint64(100), blockNumber(t, client))
+}
+
+func TestCheckEndpoints(t *testing.T) {
+	_, behind := startHeadStub(t, 5, 90)
+	_, wrongChain := startHeadStub(t, 1, 200)
+	_, best := startHeadStub(t, 5, 100)
+	statuses := CheckEndpoints(context.Background(), []string{best, behind, wrongChain, failingEndpoint(t)}, 0)
+	require.Equal(t, "ok", statuses[0].Status)
+	require.True(t, statuses[0].Healthy)
+	require.Equal(t, uint64(100), statuses[0].Head)
+	require.Equal(t, "lagging 10 blocks", statuses[1].Status)
+	require.Equal(t, "wrong chain id (expected 5)", statuses[2].Status)
+	require.Error(t, statuses[3].Err)
+	require.False(t, statuses[3].Healthy)
+	require.Equal(t, []int{0, 1, 2, 3}, rankEndpoints(statuses))
+}
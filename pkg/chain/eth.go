@@ -8,6 +8,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"math/big"
@@ -16,11 +17,23 @@ import (
 
 type Eth struct {
 	Client    *ethclient.Client
-	legacy    bool
-	noop      bool
-	confirm   bool
-	gas       uint64
-	gasTipCap *big.Int
+	rpcClient *rpc.Client
+	// Cache stores the immutable data (nil: no cache).
+	Cache *Cache
+	// Nonces reserves the nonces of the sent transactions (nil: pending nonce of the node is used).
+	Nonces *NonceManager
+	// WaitConfirmations and WaitTimeout are the settings of the waiter of the sent transactions (see Waiter).
+	WaitConfirmations uint64
+	WaitTimeout       time.Duration
+
+	chain   types.ChainConfig
+	cacheID *int64
+	// txType is the default type of the sent transactions.
+	txType  uint8
+	noop    bool
+	confirm bool
+	gas     uint64
+	fees    FeeSettings
 }
 
 func (c *Eth) SendQuery(ctx context.Context, from common.Address, to common.Address, options ...interface{}) ([]byte, error) {
@@ -43,16 +56,72 @@ func (c *Eth) GetAccountInfo(ctx context.Context, account common.Address) (types
 
 var _ ChainClient = &Eth{}
 
-func NewEth(url string, confirm bool, gas uint64, gasTipCap *big.Int) (*Eth, error) {
-	client, err := ethclient.Dial(url)
+// Multicall returns the batching layer for read-only calls.
+func (c *Eth) Multicall() *Multicall {
+	return NewMulticall(c, c.batchCaller())
+}
+
+// batchCaller returns the JSON-RPC batch transport (or nil if it's not available).
+func (c *Eth) batchCaller() BatchCaller {
+	if c.rpcClient == nil {
+		return nil
+	}
+	return c.rpcClient
+}
+
+// BlocksByNumber downloads the blocks with batched requests.
+func (c *Eth) BlocksByNumber(ctx context.Context, numbers []*big.Int) ([]*ethtypes.Block, error) {
+	if c.rpcClient == nil {
+		var blocks []*ethtypes.Block
+		for _, n := range numbers {
+			block, err := c.Client.BlockByNumber(ctx, n)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, block)
+		}
+		return blocks, nil
+	}
+	return BlocksByNumber(ctx, c.rpcClient, numbers)
+}
+
+// Balances returns the balances of the accounts with batched requests.
+func (c *Eth) Balances(ctx context.Context, accounts []common.Address) ([]*big.Int, error) {
+	if c.rpcClient == nil {
+		var balances []*big.Int
+		for _, a := range accounts {
+			balance, err := c.Client.BalanceAt(ctx, a, nil)
+			if err != nil {
+				return nil, err
+			}
+			balances = append(balances, balance)
+		}
+		return balances, nil
+	}
+	return Balances(ctx, c.rpcClient, accounts)
+}
+
+// NewEth creates the client for all the RPC endpoints of the chain (see DialChain).
+func NewEth(cfg types.ChainConfig, confirm bool, gas uint64, fees FeeSettings) (*Eth, error) {
+	txType, err := ParseTxType(cfg.TxType)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Couldn't create ethereum client with url %s", url)
+		return nil, err
+	}
+	rpcClient, err := DialChain(context.Background(), cfg)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Couldn't create ethereum client for chain %s", cfg.Name)
 	}
 	return &Eth{
 		confirm:   confirm,
-		Client:    client,
+		Client:    ethclient.NewClient(rpcClient),
+		rpcClient: rpcClient,
+		chain:     cfg,
 		gas:       gas,
-		gasTipCap: gasTipCap,
+		fees:      fees,
+		txType:    txType,
+
+		WaitConfirmations: 1,
+		WaitTimeout:       5 * time.Minute,
 	}, nil
 
 }
@@ -65,11 +134,11 @@ func (c *Eth) Query(ctx context.Context, resolver types.AddressResolver, sender
 	return Query(ctx, c.Client, resolver, sender, contract, function, args...)
 }
 
-func (c *Eth) SendTransaction(ctx context.Context, from types.Account, to *common.Address, options ...interface{}) (common.Hash, error) {
+func (c *Eth) SendTransaction(ctx context.Context, from types.Signer, to *common.Address, options ...interface{}) (common.Hash, error) {
 	return c.sendRawTransaction(ctx, from, to, options...)
 }
 
-func (c *Eth) Call(ctx context.Context, sender types.Account, contract common.Address, function string, argTypes abi.Arguments, args ...interface{}) (*ethtypes.Receipt, error) {
+func (c *Eth) Call(ctx context.Context, sender types.Signer, contract common.Address, function string, argTypes abi.Arguments, args ...interface{}) (*ethtypes.Receipt, error) {
 	if c.confirm {
 		fmt.Printf("function:      %s\n", function)
 		for i, a := range argTypes {
@@ -86,17 +155,21 @@ func (c *Eth) Call(ctx context.Context, sender types.Account, contract common.Ad
 		return nil, errors.Wrap(err, "CallContract is failed")
 	}
 
-	var receipt *ethtypes.Receipt
-	for {
-		receipt, err = c.Client.TransactionReceipt(ctx, txHash)
-		if err != nil {
-			time.Sleep(1 * time.Second)
-			continue
-		}
-		break
-
+	res, err := c.Waiter().Wait(ctx, txHash)
+	if err != nil {
+		return nil, err
 	}
-	return receipt, nil
+	if res.Receipt == nil {
+		return nil, errors.Errorf("Transaction %s is %s", txHash, res.Status)
+	}
+	return res.Receipt, nil
+}
+
+func (c *Eth) Waiter() *TxWaiter {
+	w := NewTxWaiter(c.Client)
+	w.Confirmations = c.WaitConfirmations
+	w.Timeout = c.WaitTimeout
+	return w
 }
 
 func (c *Eth) Balance(ctx context.Context, account common.Address) (decimal.Decimal, error) {
@@ -116,22 +189,35 @@ func (c *Eth) TokenBalance(ctx context.Context, token common.Address, account co
 	return res[0].(*big.Int), nil
 }
 
+// TokenInfo returns the symbol and decimals of the token. Complete results are cached.
 func (c *Eth) TokenInfo(ctx context.Context, token common.Address) (TokenInfo, error) {
 	t := TokenInfo{}
+	chainID := c.cacheChainID(ctx)
+	if c.Cache.Get(chainID, "token", token.Hex(), &t) {
+		return t, nil
+	}
+	complete := true
 	symbol, err := c.Query(ctx, types.WithoutAddressResolution{}, token, token, "symbol()string")
 	if err == nil {
 		t.Symbol = symbol[0].(string)
+	} else {
+		complete = false
 	}
 	dec, err := c.Query(ctx, types.WithoutAddressResolution{}, token, token, "decimals()uint8")
 	if err == nil {
 		t.Decimal = dec[0].(uint8)
+	} else {
+		complete = false
 	}
 	t.Address = token
+	if complete {
+		c.Cache.Put(chainID, "token", token.Hex(), t)
+	}
 	return t, nil
 }
 
-func (c *Eth) GetTransaction(ctx context.Context, hash common.Hash) (types.Item, error) {
-	return GetTransaction(ctx, c.Client, hash)
+func (c *Eth) GetTransaction(ctx context.Context, hash common.Hash) (TransactionDetails, error) {
+	return GetTransaction(ctx, c.Client, c, hash)
 }
 
 func optionalAddress(to *common.Address) string {
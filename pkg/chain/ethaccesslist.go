@@ -0,0 +1,176 @@
+package chain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"math/big"
+)
+
+// AccessListEstimate is the access list created by the node, with the estimated gas of the transaction with and
+// without the access list.
+type AccessListEstimate struct {
+	AccessList ethtypes.AccessList
+	Gas        uint64
+	GasWithout uint64
+}
+
+// Savings returns the gas saved by the access list (negative, if the access list makes the transaction more expensive).
+func (e AccessListEstimate) Savings() int64 {
+	return int64(e.GasWithout) - int64(e.Gas)
+}
+
+func (c *Eth) prepareAccessListTx(ctx context.Context, from common.Address, to *common.Address, opts ...interface{}) (*ethtypes.Transaction, *big.Int, error) {
+	nonce, err := c.Client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chainID, err := c.getChainId(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gasPrice := c.fees.GasFeeCap
+	if gasPrice == nil {
+		gasPrice, err = c.Client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Couldn't get suggested gas price")
+		}
+	}
+
+	tx := ethtypes.AccessListTx{
+		ChainID:  chainID,
+		To:       to,
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Gas:      c.gas,
+	}
+
+	err = optionForAccessListTx(&tx, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tx.Gas == 0 {
+		gas, err := c.estimateGas(ctx, ethereum.CallMsg{
+			From:       from,
+			To:         to,
+			Data:       tx.Data,
+			Value:      tx.Value,
+			AccessList: tx.AccessList,
+		})
+		if err != nil {
+			return nil, nil, errors.Wrap(NewRevertError(err), "Couldn't estimate gas")
+		}
+		tx.Gas = gas * 13 / 10
+	}
+
+	return ethtypes.NewTx(&tx), chainID, nil
+}
+
+func optionForAccessListTx(tx *ethtypes.AccessListTx, opts ...interface{}) error {
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case WithData:
+			tx.Data = o.Data
+		case WithValue:
+			tx.Value = o.Value
+		case WithNonce:
+			tx.Nonce = o.Nonce
+		case WithGas:
+			tx.Gas = o.Gas
+		case WithGasPrice:
+			tx.GasPrice = o.Price
+		case WithAccessList:
+			tx.AccessList = o.List
+		case WithTxType:
+		default:
+			return errors.Errorf("Unsupported option type %T", opt)
+		}
+	}
+	return nil
+}
+
+// CreateAccessList creates the access list of the transaction with eth_createAccessList, and estimates the gas with
+// and without the list (only the data and value options are used).
+func (c *Eth) CreateAccessList(ctx context.Context, from common.Address, to *common.Address, opts ...interface{}) (AccessListEstimate, error) {
+	if c.rpcClient == nil {
+		return AccessListEstimate{}, errors.New("eth_createAccessList requires RPC client")
+	}
+	msg := ethereum.CallMsg{
+		From: from,
+		To:   to,
+	}
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case WithData:
+			msg.Data = o.Data
+		case WithValue:
+			msg.Value = o.Value
+		}
+	}
+
+	res := struct {
+		AccessList ethtypes.AccessList `json:"accessList"`
+		GasUsed    hexutil.Uint64      `json:"gasUsed"`
+		Error      string              `json:"error,omitempty"`
+	}{}
+	err := c.rpcClient.CallContext(ctx, &res, "eth_createAccessList", callArgs(msg), "pending")
+	if err != nil {
+		return AccessListEstimate{}, errors.Wrap(err, "Couldn't create access list")
+	}
+	if res.Error != "" {
+		return AccessListEstimate{}, errors.Errorf("Couldn't create access list: %s", res.Error)
+	}
+
+	e := AccessListEstimate{
+		AccessList: res.AccessList,
+	}
+	e.GasWithout, err = c.estimateGas(ctx, msg)
+	if err != nil {
+		return AccessListEstimate{}, errors.Wrap(NewRevertError(err), "Couldn't estimate gas")
+	}
+	msg.AccessList = res.AccessList
+	e.Gas, err = c.estimateGas(ctx, msg)
+	if err != nil {
+		return AccessListEstimate{}, errors.Wrap(NewRevertError(err), "Couldn't estimate gas with access list")
+	}
+	return e, nil
+}
+
+// estimateGas estimates the gas of the message including the access list (which is ignored by ethclient).
+func (c *Eth) estimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	if len(msg.AccessList) == 0 || c.rpcClient == nil {
+		return c.Client.EstimateGas(ctx, msg)
+	}
+	var gas hexutil.Uint64
+	err := c.rpcClient.CallContext(ctx, &gas, "eth_estimateGas", callArgs(msg))
+	if err != nil {
+		return 0, err
+	}
+	return uint64(gas), nil
+}
+
+// callArgs is the JSON-RPC representation of the message.
+func callArgs(msg ethereum.CallMsg) map[string]interface{} {
+	args := map[string]interface{}{
+		"from": msg.From,
+	}
+	if msg.To != nil {
+		args["to"] = msg.To
+	}
+	if len(msg.Data) > 0 {
+		args["data"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		args["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if len(msg.AccessList) > 0 {
+		args["accessList"] = msg.AccessList
+	}
+	return args
+}
@@ -0,0 +1,88 @@
+package chain
+
+import (
+	"context"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+)
+
+// accessListStub is a node where the access list saves 2000 gas.
+type accessListStub struct {
+	list ethtypes.AccessList
+}
+
+type accessListResult struct {
+	AccessList ethtypes.AccessList `json:"accessList"`
+	GasUsed    hexutil.Uint64      `json:"gasUsed"`
+}
+
+func (s *accessListStub) CreateAccessList(args map[string]interface{}, block string) accessListResult {
+	return accessListResult{AccessList: s.list, GasUsed: 30000}
+}
+
+func (s *accessListStub) EstimateGas(args map[string]interface{}) hexutil.Uint64 {
+	if list, ok := args["accessList"].([]interface{}); ok && len(list) > 0 {
+		return 30000
+	}
+	return 32000
+}
+
+func (s *accessListStub) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(5))
+}
+
+func (s *accessListStub) GetTransactionCount(account common.Address, block string) hexutil.Uint64 {
+	return 7
+}
+
+func (s *accessListStub) GasPrice() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(1_000_000_000))
+}
+
+func TestCreateAccessList(t *testing.T) {
+	to := common.HexToAddress("0x02")
+	stub := &accessListStub{
+		list: ethtypes.AccessList{{Address: to, StorageKeys: []common.Hash{common.HexToHash("0x01")}}},
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	require.NoError(t, server.RegisterName("eth", stub))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	eth, err := NewEth(types.ChainConfig{RPCURL: httpServer.URL, ChainID: 5, TxType: "legacy"}, false, 0, FeeSettings{})
+	require.NoError(t, err)
+	ctx := context.Background()
+	from := common.HexToAddress("0x01")
+
+	estimate, err := eth.CreateAccessList(ctx, from, &to, WithData{Data: []byte{1, 2}})
+	require.NoError(t, err)
+	require.Equal(t, stub.list, estimate.AccessList)
+	require.Equal(t, uint64(32000), estimate.GasWithout)
+	require.Equal(t, int64(2000), estimate.Savings())
+
+	// legacy chain uses EIP-2930 transaction for access list
+	tx, _, err := eth.PrepareTransaction(ctx, from, &to, WithData{Data: []byte{1, 2}}, WithAccessList{List: estimate.AccessList})
+	require.NoError(t, err)
+	require.Equal(t, uint8(ethtypes.AccessListTxType), tx.Type())
+	require.Equal(t, stub.list, tx.AccessList())
+	require.Equal(t, uint64(7), tx.Nonce())
+	require.Equal(t, uint64(39000), tx.Gas())
+
+	// legacy transaction estimates the gas too
+	tx, _, err = eth.PrepareTransaction(ctx, from, &to, WithData{Data: []byte{1, 2}})
+	require.NoError(t, err)
+	require.Equal(t, uint8(ethtypes.LegacyTxType), tx.Type())
+	require.Equal(t, uint64(41600), tx.Gas())
+	require.Equal(t, big.NewInt(1_000_000_000), tx.GasPrice())
+
+	_, err = ParseTxType("eip4844")
+	require.Error(t, err)
+}
@@ -0,0 +1,173 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"math/big"
+	"net/url"
+	"strings"
+)
+
+var (
+	// FinalityDepth is the number of confirmations after a receipt is cached.
+	FinalityDepth uint64 = 64
+
+	// devChainIDs are the chain IDs of local development chains which can be restarted with different state.
+	devChainIDs = map[int64]bool{
+		1337:  true,
+		31337: true,
+	}
+)
+
+// cachedTransaction is the cache entry of a finalized transaction.
+type cachedTransaction struct {
+	Tx      *ethtypes.Transaction `json:"tx"`
+	Receipt *ethtypes.Receipt     `json:"receipt"`
+}
+
+// cacheChainID returns the chain ID used as the cache namespace, or 0 if caching is turned off.
+func (c *Eth) cacheChainID(ctx context.Context) int64 {
+	if c.Cache == nil {
+		return 0
+	}
+	if c.cacheID != nil {
+		return *c.cacheID
+	}
+	chainID := c.chain.ChainID
+	endpoints := c.chain.Endpoints()
+	if chainID == 0 && len(endpoints) > 0 {
+		if id, found := c.Cache.EndpointChainID(endpoints[0]); found {
+			chainID = id
+		}
+	}
+	if chainID == 0 {
+		id, err := c.Client.ChainID(ctx)
+		if err != nil {
+			log.Debug().Err(err).Msg("Couldn't get chain ID, cache is not used")
+			return 0
+		}
+		chainID = id.Int64()
+		if len(endpoints) > 0 && !isLocal(endpoints[0]) {
+			c.Cache.SetEndpointChainID(endpoints[0], chainID)
+		}
+	}
+	if devChainIDs[chainID] {
+		chainID = 0
+	}
+	c.cacheID = &chainID
+	return chainID
+}
+
+// isLocal returns true for the endpoints of the local machine (which may serve different chains over time).
+func isLocal(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Hostname() == "" {
+		return true
+	}
+	host := u.Hostname()
+	return host == "localhost" || host == "::1" || strings.HasPrefix(host, "127.")
+}
+
+// TransactionAndReceipt returns the transaction and the receipt (nil, if pending). Transactions with enough
+// confirmations are cached.
+func (c *Eth) TransactionAndReceipt(ctx context.Context, hash common.Hash) (*ethtypes.Transaction, *ethtypes.Receipt, error) {
+	chainID := c.cacheChainID(ctx)
+	var cached cachedTransaction
+	if c.Cache.Get(chainID, "tx", hash.Hex(), &cached) && cached.Tx != nil && cached.Receipt != nil {
+		return cached.Tx, cached.Receipt, nil
+	}
+
+	var head hexutil.Uint64
+	var tx *ethtypes.Transaction
+	var receipt *ethtypes.Receipt
+	var err error
+	if batch := c.batchCaller(); batch != nil {
+		tx, receipt, err = transactionAndReceipt(ctx, batch, hash, &head)
+	} else {
+		tx, receipt, err = clientSource{client: c.Client}.TransactionAndReceipt(ctx, hash)
+		if err == nil && receipt != nil && chainID != 0 {
+			var number uint64
+			number, err = c.Client.BlockNumber(ctx)
+			head = hexutil.Uint64(number)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if receipt != nil && receipt.BlockNumber != nil && receipt.BlockNumber.Uint64()+FinalityDepth <= uint64(head) {
+		c.Cache.Put(chainID, "tx", hash.Hex(), cachedTransaction{Tx: tx, Receipt: receipt})
+	}
+	return tx, receipt, nil
+}
+
+// HeaderByHash returns the (cached) block header.
+func (c *Eth) HeaderByHash(ctx context.Context, hash common.Hash) (*ethtypes.Header, error) {
+	chainID := c.cacheChainID(ctx)
+	header := &ethtypes.Header{}
+	if c.Cache.Get(chainID, "header", hash.Hex(), header) {
+		return header, nil
+	}
+	header, err := c.Client.HeaderByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	c.Cache.Put(chainID, "header", hash.Hex(), header)
+	return header, nil
+}
+
+// BlockByHash returns the (cached) block with the transactions.
+func (c *Eth) BlockByHash(ctx context.Context, hash common.Hash) (*ethtypes.Block, error) {
+	if c.rpcClient == nil {
+		return c.Client.BlockByHash(ctx, hash)
+	}
+	chainID := c.cacheChainID(ctx)
+	var raw json.RawMessage
+	if c.Cache.Get(chainID, "block", hash.Hex(), &raw) {
+		if block, err := decodeBlock(raw); err == nil {
+			return block, nil
+		}
+	}
+	err := c.rpcClient.CallContext(ctx, &raw, "eth_getBlockByHash", hash, true)
+	if err != nil {
+		return nil, err
+	}
+	block, err := decodeBlock(raw)
+	if err != nil {
+		if errors.Is(err, ethereum.NotFound) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "Couldn't decode block")
+	}
+	c.Cache.Put(chainID, "block", hash.Hex(), raw)
+	return block, nil
+}
+
+// CodeAt returns the code of the contract. Code of the latest block is cached, if the contract is deployed.
+func (c *Eth) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	if blockNumber != nil {
+		return c.Client.CodeAt(ctx, contract, blockNumber)
+	}
+	chainID := c.cacheChainID(ctx)
+	var code hexutil.Bytes
+	if c.Cache.Get(chainID, "code", contract.Hex(), &code) {
+		return code, nil
+	}
+	code, err := c.Client.CodeAt(ctx, contract, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) > 0 {
+		c.Cache.Put(chainID, "code", contract.Hex(), code)
+	}
+	return code, nil
+}
+
+func (c *Eth) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return c.Client.CallContract(ctx, msg, blockNumber)
+}
@@ -14,28 +14,58 @@ import (
 	"math/big"
 )
 
-func (c *Eth) sendRawTransaction(ctx context.Context, sender types.Account, to *common.Address, opts ...interface{}) (hash common.Hash, err error) {
-	nonce, err := c.Client.PendingNonceAt(ctx, sender.Address())
+// PrepareTransaction creates the unsigned transaction with all the fields (nonce, fees, gas) filled from the node.
+func (c *Eth) PrepareTransaction(ctx context.Context, from common.Address, to *common.Address, opts ...interface{}) (*ethtypes.Transaction, *big.Int, error) {
+	txType := c.txType
+	accessList := false
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case WithTxType:
+			txType = o.Type
+		case WithAccessList:
+			accessList = len(o.List) > 0
+		}
+	}
+	if txType == ethtypes.LegacyTxType && accessList {
+		txType = ethtypes.AccessListTxType
+	}
+	var tx *ethtypes.Transaction
+	var chainID *big.Int
+	var err error
+	switch txType {
+	case ethtypes.LegacyTxType:
+		tx, chainID, err = c.prepareLegacyTx(ctx, from, to, opts...)
+	case ethtypes.AccessListTxType:
+		tx, chainID, err = c.prepareAccessListTx(ctx, from, to, opts...)
+	case ethtypes.DynamicFeeTxType:
+		tx, chainID, err = c.prepareDynamicTx(ctx, from, to, opts...)
+	default:
+		return nil, nil, errors.Errorf("Unsupported transaction type %d", txType)
+	}
 	if err != nil {
-		return hash, err
+		return nil, nil, err
+	}
+	err = c.checkCost(tx)
+	if err != nil {
+		return nil, nil, err
 	}
+	return tx, chainID, nil
+}
 
-	chainID, err := c.getChainId(ctx)
+func (c *Eth) prepareDynamicTx(ctx context.Context, from common.Address, to *common.Address, opts ...interface{}) (*ethtypes.Transaction, *big.Int, error) {
+	nonce, err := c.Client.PendingNonceAt(ctx, from)
 	if err != nil {
-		return hash, err
+		return nil, nil, err
 	}
 
-	baseGas, err := c.Client.SuggestGasPrice(ctx)
+	chainID, err := c.getChainId(ctx)
 	if err != nil {
-		return hash, errors.Wrap(err, "Couldn't get suggested gas price")
+		return nil, nil, err
 	}
 
-	tip := c.gasTipCap
-	if tip == nil {
-		tip, err = c.Client.SuggestGasTipCap(ctx)
-		if err != nil {
-			return hash, errors.Wrap(err, "Couldn't get suggested gas price")
-		}
+	tip, feeCap, err := c.suggestFees(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	tx := ethtypes.DynamicFeeTx{
@@ -43,31 +73,52 @@ func (c *Eth) sendRawTransaction(ctx context.Context, sender types.Account, to *
 		ChainID:   chainID,
 		Nonce:     nonce,
 		GasTipCap: tip,
+		Gas:       c.gas,
 	}
 
 	err = optionForDynamicTx(&tx, opts...)
 	if err != nil {
-		return hash, err
+		return nil, nil, err
 	}
 
-	gas := c.gas
-	if gas == 0 {
-		gas, err = c.Client.EstimateGas(context.Background(), ethereum.CallMsg{
-			From: sender.Address(),
-			To:   to,
-			Data: tx.Data,
+	if tx.Gas == 0 {
+		gas, err := c.estimateGas(ctx, ethereum.CallMsg{
+			From:       from,
+			To:         to,
+			Data:       tx.Data,
+			Value:      tx.Value,
+			AccessList: tx.AccessList,
 		})
 		if err != nil {
-			return hash, err
+			return nil, nil, errors.Wrap(NewRevertError(err), "Couldn't estimate gas")
 		}
-		gas = gas * 13 / 10
+		tx.Gas = gas * 13 / 10
+	}
+
+	if tx.GasFeeCap == nil {
+		tx.GasFeeCap = feeCap(tx.GasTipCap)
 	}
-	tx.Gas = uint64(gas)
 
-	tx.GasFeeCap = new(big.Int).Add(new(big.Int).Mul(baseGas, big.NewInt(2)), tx.GasTipCap)
+	return ethtypes.NewTx(&tx), chainID, nil
+}
 
-	newTx := ethtypes.NewTx(&tx)
-	signedTx, err := ethtypes.SignTx(newTx, ethtypes.NewLondonSigner(chainID), sender.PrivateKey())
+func (c *Eth) sendRawTransaction(ctx context.Context, sender types.Signer, to *common.Address, opts ...interface{}) (hash common.Hash, err error) {
+	opts, release, err := c.reserveNonce(ctx, sender.Address(), opts)
+	if err != nil {
+		return hash, err
+	}
+	defer func() {
+		if err != nil {
+			release()
+		}
+	}()
+
+	newTx, chainID, err := c.PrepareTransaction(ctx, sender.Address(), to, opts...)
+	if err != nil {
+		return hash, err
+	}
+
+	signedTx, err := sender.SignTx(newTx, chainID)
 	if err != nil {
 		return hash, errors.Wrap(err, "Couldn't sign the transaction")
 	}
@@ -100,6 +151,44 @@ func (c *Eth) sendRawTransaction(ctx context.Context, sender types.Account, to *
 		}
 
 	}
+	return c.SendSignedTransaction(ctx, signedTx)
+}
+
+// reserveNonce adds the next free nonce of the account from the nonce manager to the options (if the nonce is not
+// defined explicitly). The returned function releases the reservation if the transaction is not sent.
+func (c *Eth) reserveNonce(ctx context.Context, from common.Address, opts []interface{}) ([]interface{}, func(), error) {
+	none := func() {}
+	if c.Nonces == nil {
+		return opts, none, nil
+	}
+	for _, opt := range opts {
+		if _, ok := opt.(WithNonce); ok {
+			return opts, none, nil
+		}
+	}
+	chainID, err := c.GetChainID(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	pending, err := c.Client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Couldn't get pending nonce")
+	}
+	nonce, err := c.Nonces.Reserve(chainID, from, pending)
+	if err != nil {
+		return nil, nil, err
+	}
+	release := func() {
+		err := c.Nonces.Release(chainID, from, nonce)
+		if err != nil {
+			log.Warn().Err(err).Uint64("nonce", nonce).Msg("Couldn't release reserved nonce")
+		}
+	}
+	return append(opts, WithNonce{Nonce: nonce}), release, nil
+}
+
+// SendSignedTransaction broadcasts an already signed transaction.
+func (c *Eth) SendSignedTransaction(ctx context.Context, signedTx *ethtypes.Transaction) (hash common.Hash, err error) {
 	err = c.Client.SendTransaction(ctx, signedTx)
 	if err != nil {
 		return hash, errors.Wrap(err, "Couldn't send transaction")
@@ -123,8 +212,11 @@ func optionForDynamicTx(tx *ethtypes.DynamicFeeTx, opts ...interface{}) error {
 			tx.GasFeeCap = o.Value
 		case WithGas:
 			tx.Gas = o.Gas
+		case WithAccessList:
+			tx.AccessList = o.List
+		case WithTxType:
 		default:
-			return errors.Errorf("Unsupported option type %t:", opt)
+			return errors.Errorf("Unsupported option type %T", opt)
 		}
 	}
 	return nil
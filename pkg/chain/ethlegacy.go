@@ -2,53 +2,59 @@ package chain
 
 import (
 	"context"
-	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/pkg/errors"
+	"math/big"
 )
 
-func (c *Eth) sendRawLegacyTx(ctx context.Context, sender types.Account, to *common.Address, opts ...interface{}) (hash common.Hash, err error) {
+func (c *Eth) prepareLegacyTx(ctx context.Context, from common.Address, to *common.Address, opts ...interface{}) (*ethtypes.Transaction, *big.Int, error) {
 
-	nonce, err := c.Client.PendingNonceAt(ctx, sender.Address())
+	nonce, err := c.Client.PendingNonceAt(ctx, from)
 	if err != nil {
-		return hash, err
+		return nil, nil, err
 	}
 
 	chainId, err := c.getChainId(ctx)
 	if err != nil {
-		return hash, err
+		return nil, nil, err
 	}
 
-	gasPrice, err := c.Client.SuggestGasPrice(ctx)
-	if err != nil {
-		return hash, err
+	gasPrice := c.fees.GasFeeCap
+	if gasPrice == nil {
+		gasPrice, err = c.Client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	tx := ethtypes.LegacyTx{
 		To:       to,
 		Nonce:    nonce,
-		Gas:      8000000,
+		Gas:      c.gas,
 		GasPrice: gasPrice,
 	}
 
 	err = optionForLegacyTx(&tx, opts...)
 	if err != nil {
-		return hash, err
+		return nil, nil, err
 	}
 
-	newTx := ethtypes.NewTx(&tx)
-	signedTx, err := ethtypes.SignTx(newTx, ethtypes.NewLondonSigner(chainId), sender.PrivateKey())
-	if err != nil {
-		return hash, errors.Wrap(err, "Couldn't sign the transaction")
-	}
-
-	err = c.Client.SendTransaction(ctx, signedTx)
-	if err != nil {
-		return hash, errors.Wrap(err, "Couldn't send transaction")
+	if tx.Gas == 0 {
+		gas, err := c.Client.EstimateGas(ctx, ethereum.CallMsg{
+			From:  from,
+			To:    to,
+			Data:  tx.Data,
+			Value: tx.Value,
+		})
+		if err != nil {
+			return nil, nil, errors.Wrap(NewRevertError(err), "Couldn't estimate gas")
+		}
+		tx.Gas = gas * 13 / 10
 	}
 
-	return signedTx.Hash(), nil
+	return ethtypes.NewTx(&tx), chainId, nil
 }
 
 func optionForLegacyTx(tx *ethtypes.LegacyTx, opts ...interface{}) error {
@@ -62,8 +68,15 @@ func optionForLegacyTx(tx *ethtypes.LegacyTx, opts ...interface{}) error {
 			tx.Nonce = o.Nonce
 		case WithGas:
 			tx.Gas = o.Gas
+		case WithGasPrice:
+			tx.GasPrice = o.Price
+		case WithAccessList:
+			if len(o.List) > 0 {
+				return errors.New("Legacy transactions can't have access list")
+			}
+		case WithTxType:
 		default:
-			return errors.Errorf("Unsupported option type %t:", opt)
+			return errors.Errorf("Unsupported option type %T", opt)
 		}
 	}
 	return nil
@@ -0,0 +1,236 @@
+package chain
+
+import (
+	"context"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"math/big"
+	"sort"
+)
+
+// FeeStrategy selects the tip of the dynamic fee transactions.
+type FeeStrategy string
+
+const (
+	// FeeSlow uses the 10th percentile of the recent tips.
+	FeeSlow FeeStrategy = "slow"
+	// FeeNormal uses the median of the recent tips.
+	FeeNormal FeeStrategy = "normal"
+	// FeeFast uses the 90th percentile of the recent tips.
+	FeeFast FeeStrategy = "fast"
+	// FeeCustom uses only the explicitly defined tip and max fee.
+	FeeCustom FeeStrategy = "custom"
+)
+
+// FeeStrategies are the strategies based on the fee history.
+var FeeStrategies = []FeeStrategy{FeeSlow, FeeNormal, FeeFast}
+
+var feePercentiles = map[FeeStrategy]float64{
+	FeeSlow:   10,
+	FeeNormal: 50,
+	FeeFast:   90,
+}
+
+// Percentile returns the reward percentile of the strategy (0 for custom).
+func (s FeeStrategy) Percentile() float64 {
+	return feePercentiles[s]
+}
+
+// FeeHistoryBlocks is the number of the recent blocks used to estimate the tips.
+const FeeHistoryBlocks = 20
+
+// ParseFeeStrategy validates the name of the strategy.
+func ParseFeeStrategy(s string) (FeeStrategy, error) {
+	switch FeeStrategy(s) {
+	case FeeSlow, FeeNormal, FeeFast, FeeCustom:
+		return FeeStrategy(s), nil
+	case "":
+		return FeeNormal, nil
+	}
+	return "", errors.Errorf("Unknown fee strategy %s (use slow, normal, fast or custom)", s)
+}
+
+// FeeSettings are the user defined fee parameters of the transactions.
+type FeeSettings struct {
+	Strategy FeeStrategy
+	// GasTipCap overrides the tip of the strategy (nil: use the strategy).
+	GasTipCap *big.Int
+	// GasFeeCap overrides the max fee per gas (nil: twice of the base fee + tip). Used as gas price of legacy
+	// transactions.
+	GasFeeCap *big.Int
+	// MaxCost is the limit of the maximum transaction fee (gas limit * max fee per gas), nil: no limit.
+	MaxCost *big.Int
+}
+
+// Validate checks if the settings are consistent.
+func (s FeeSettings) Validate() error {
+	if s.Strategy == FeeCustom && (s.GasTipCap == nil || s.GasFeeCap == nil) {
+		return errors.New("Both --tip and --max-fee are required with custom fee strategy")
+	}
+	if s.GasTipCap != nil && s.GasFeeCap != nil && s.GasFeeCap.Cmp(s.GasTipCap) < 0 {
+		return errors.New("Max fee should be higher than the tip")
+	}
+	return nil
+}
+
+// FeeHistory is the result of eth_feeHistory.
+type FeeHistory struct {
+	OldestBlock *big.Int
+	// Reward has the requested percentiles of the tips for each block.
+	Reward [][]*big.Int
+	// BaseFee has the base fee of the blocks, and the expected base fee of the next block.
+	BaseFee      []*big.Int
+	GasUsedRatio []float64
+}
+
+// FeeEstimate is the fee level of the recent blocks.
+type FeeEstimate struct {
+	// BaseFee is the expected base fee of the next block.
+	BaseFee *big.Int
+	// Tips are the suggested tips of the strategies.
+	Tips map[FeeStrategy]*big.Int
+}
+
+// FeeHistory returns the tips of the recent blocks at the given percentiles.
+func (c *Eth) FeeHistory(ctx context.Context, blocks uint64, percentiles []float64) (*FeeHistory, error) {
+	if c.rpcClient == nil {
+		return nil, errors.New("eth_feeHistory requires RPC client")
+	}
+	res := struct {
+		OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+		Reward       [][]*hexutil.Big `json:"reward"`
+		BaseFee      []*hexutil.Big   `json:"baseFeePerGas"`
+		GasUsedRatio []float64        `json:"gasUsedRatio"`
+	}{}
+	err := c.rpcClient.CallContext(ctx, &res, "eth_feeHistory", hexutil.Uint64(blocks), "latest", percentiles)
+	if err != nil {
+		return nil, errors.Wrap(err, "Couldn't get fee history")
+	}
+	h := &FeeHistory{
+		OldestBlock:  (*big.Int)(res.OldestBlock),
+		GasUsedRatio: res.GasUsedRatio,
+	}
+	for _, rewards := range res.Reward {
+		var r []*big.Int
+		for _, reward := range rewards {
+			r = append(r, (*big.Int)(reward))
+		}
+		h.Reward = append(h.Reward, r)
+	}
+	for _, fee := range res.BaseFee {
+		h.BaseFee = append(h.BaseFee, (*big.Int)(fee))
+	}
+	return h, nil
+}
+
+// EstimateFees returns the suggested tips of the strategies based on the recent blocks.
+func (c *Eth) EstimateFees(ctx context.Context, blocks uint64) (FeeEstimate, error) {
+	var percentiles []float64
+	for _, s := range FeeStrategies {
+		percentiles = append(percentiles, s.Percentile())
+	}
+	h, err := c.FeeHistory(ctx, blocks, percentiles)
+	if err != nil {
+		return FeeEstimate{}, err
+	}
+	return EstimateFees(h)
+}
+
+// EstimateFees calculates the tips of the strategies from the fee history (requested with the percentiles of
+// FeeStrategies). The tip of a strategy is the median of the percentile of the non-empty blocks.
+func EstimateFees(h *FeeHistory) (FeeEstimate, error) {
+	if len(h.BaseFee) == 0 || h.BaseFee[len(h.BaseFee)-1] == nil {
+		return FeeEstimate{}, errors.New("Fee history doesn't have base fee (EIP-1559 is not supported)")
+	}
+	e := FeeEstimate{
+		BaseFee: h.BaseFee[len(h.BaseFee)-1],
+		Tips:    map[FeeStrategy]*big.Int{},
+	}
+	for ix, strategy := range FeeStrategies {
+		var tips []*big.Int
+		for block, rewards := range h.Reward {
+			if block < len(h.GasUsedRatio) && h.GasUsedRatio[block] == 0 {
+				continue
+			}
+			if ix < len(rewards) && rewards[ix] != nil {
+				tips = append(tips, rewards[ix])
+			}
+		}
+		if len(tips) == 0 {
+			return FeeEstimate{}, errors.New("Fee history doesn't have any non-empty block")
+		}
+		sort.Slice(tips, func(i, j int) bool {
+			return tips[i].Cmp(tips[j]) < 0
+		})
+		e.Tips[strategy] = tips[len(tips)/2]
+	}
+	return e, nil
+}
+
+// MaxFee returns the max fee per gas for the tip: twice of the base fee (to survive a few full blocks) + the tip.
+func (e FeeEstimate) MaxFee(tip *big.Int) *big.Int {
+	return new(big.Int).Add(new(big.Int).Mul(e.BaseFee, big.NewInt(2)), tip)
+}
+
+// suggestFees returns the tip of the next dynamic fee transaction based on the fee settings, and the function which
+// calculates the max fee per gas for the final tip (which can be overridden by the transaction options).
+func (c *Eth) suggestFees(ctx context.Context) (tip *big.Int, feeCap func(tip *big.Int) *big.Int, err error) {
+	if c.fees.GasFeeCap != nil {
+		feeCap = func(*big.Int) *big.Int {
+			return c.fees.GasFeeCap
+		}
+	}
+	if c.fees.Strategy == FeeCustom {
+		return c.fees.GasTipCap, feeCap, nil
+	}
+
+	strategy := c.fees.Strategy
+	if strategy == "" {
+		strategy = FeeNormal
+	}
+	estimate, err := c.EstimateFees(ctx, FeeHistoryBlocks)
+	if err != nil {
+		// some chains don't support eth_feeHistory
+		log.Debug().Err(err).Msg("Fee history is not available, using the suggestions of the node")
+		head, err := c.Client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Couldn't get the latest block")
+		}
+		if head.BaseFee == nil {
+			return nil, nil, errors.New("Chain doesn't support dynamic fee transactions (EIP-1559)")
+		}
+		estimate = FeeEstimate{BaseFee: head.BaseFee, Tips: map[FeeStrategy]*big.Int{}}
+		estimate.Tips[strategy], err = c.Client.SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Couldn't get suggested gas tip")
+		}
+	}
+
+	tip = estimate.Tips[strategy]
+	if c.fees.GasTipCap != nil {
+		tip = c.fees.GasTipCap
+	}
+	if c.fees.GasFeeCap != nil && tip.Cmp(c.fees.GasFeeCap) > 0 {
+		tip = c.fees.GasFeeCap
+	}
+	if feeCap == nil {
+		feeCap = estimate.MaxFee
+	}
+	return tip, feeCap, nil
+}
+
+// checkCost returns error if the maximum fee of the transaction is higher than the limit of the fee settings.
+func (c *Eth) checkCost(tx *ethtypes.Transaction) error {
+	if c.fees.MaxCost == nil {
+		return nil
+	}
+	cost := new(big.Int).Mul(tx.GasFeeCap(), new(big.Int).SetUint64(tx.Gas()))
+	if cost.Cmp(c.fees.MaxCost) > 0 {
+		return errors.Errorf("Maximum transaction fee %s (gas %d * max fee %s) exceeds the limit %s",
+			types.PrettyETH(cost), tx.Gas(), types.PrettyETH(tx.GasFeeCap()), types.PrettyETH(c.fees.MaxCost))
+	}
+	return nil
+}
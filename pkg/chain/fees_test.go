@@ -0,0 +1,89 @@
+package chain
+
+import (
+	"context"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+)
+
+// feeHistoryStub returns the same fee history for any eth_feeHistory request.
+type feeHistoryStub struct {
+	percentiles []float64
+}
+
+type feeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward"`
+	BaseFee      []*hexutil.Big   `json:"baseFeePerGas"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+func (s *feeHistoryStub) FeeHistory(blocks hexutil.Uint64, last string, percentiles []float64) feeHistoryResult {
+	s.percentiles = percentiles
+	gwei := func(v int64) *hexutil.Big {
+		return (*hexutil.Big)(big.NewInt(v * 1_000_000_000))
+	}
+	return feeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(big.NewInt(100)),
+		Reward:       [][]*hexutil.Big{{gwei(1), gwei(2), gwei(9)}, {gwei(0), gwei(0), gwei(0)}, {gwei(1), gwei(3), gwei(5)}, {gwei(2), gwei(2), gwei(4)}},
+		BaseFee:      []*hexutil.Big{gwei(10), gwei(11), gwei(10), gwei(10), gwei(12)},
+		GasUsedRatio: []float64{0.5, 0, 0.7, 0.4},
+	}
+}
+
+func TestEstimateFees(t *testing.T) {
+	stub := &feeHistoryStub{}
+	server := rpc.NewServer()
+	defer server.Stop()
+	require.NoError(t, server.RegisterName("eth", stub))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	eth, err := NewEth(types.ChainConfig{RPCURL: httpServer.URL, ChainID: 5}, false, 0, FeeSettings{Strategy: FeeFast})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	estimate, err := eth.EstimateFees(ctx, FeeHistoryBlocks)
+	require.NoError(t, err)
+	require.Equal(t, []float64{10, 50, 90}, stub.percentiles)
+	require.Equal(t, big.NewInt(12_000_000_000), estimate.BaseFee)
+	// empty block is ignored
+	require.Equal(t, big.NewInt(1_000_000_000), estimate.Tips[FeeSlow])
+	require.Equal(t, big.NewInt(2_000_000_000), estimate.Tips[FeeNormal])
+	require.Equal(t, big.NewInt(5_000_000_000), estimate.Tips[FeeFast])
+
+	tip, feeCap, err := eth.suggestFees(ctx)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(5_000_000_000), tip)
+	require.Equal(t, big.NewInt(29_000_000_000), feeCap(tip))
+
+	eth.fees = FeeSettings{Strategy: FeeCustom, GasTipCap: big.NewInt(7), GasFeeCap: big.NewInt(100)}
+	tip, feeCap, err = eth.suggestFees(ctx)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(7), tip)
+	require.Equal(t, big.NewInt(100), feeCap(tip))
+}
+
+func TestFeeSettings(t *testing.T) {
+	_, err := ParseFeeStrategy("fastest")
+	require.Error(t, err)
+	strategy, err := ParseFeeStrategy("")
+	require.NoError(t, err)
+	require.Equal(t, FeeNormal, strategy)
+
+	require.Error(t, FeeSettings{Strategy: FeeCustom, GasTipCap: big.NewInt(1)}.Validate())
+	require.Error(t, FeeSettings{Strategy: FeeSlow, GasTipCap: big.NewInt(2), GasFeeCap: big.NewInt(1)}.Validate())
+	require.NoError(t, FeeSettings{Strategy: FeeSlow, GasTipCap: big.NewInt(2)}.Validate())
+
+	eth := &Eth{fees: FeeSettings{MaxCost: big.NewInt(1_000_000)}}
+	tx := ethtypes.NewTx(&ethtypes.DynamicFeeTx{Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(47)})
+	require.NoError(t, eth.checkCost(tx))
+	tx = ethtypes.NewTx(&ethtypes.DynamicFeeTx{Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(48)})
+	require.Error(t, eth.checkCost(tx))
+}
@@ -0,0 +1,349 @@
+package chain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// LogSource is the part of the ethclient.Client which is required to follow the logs.
+type LogSource interface {
+	HeadSource
+	LogFilterer
+}
+
+// LogHandler receives the logs of the processed block range. Removed logs (due to reorg) are marked with Removed=true.
+// Processed is the last block which is fully processed (can be saved as checkpoint).
+type LogHandler func(logs []ethtypes.Log, processed uint64, hash common.Hash) error
+
+// LogFollower downloads the logs from a starting block, and follows the new blocks of the chain.
+type LogFollower struct {
+	client LogSource
+	// Query defines the addresses and topics (block range is ignored).
+	Query ethereum.FilterQuery
+	// Window is used to download the older blocks during the catch-up.
+	Window  *LogWindow
+	Watcher *HeadWatcher
+
+	// recent are the handled logs of the last blocks (to report them as removed in case of reorg).
+	recent map[common.Hash][]ethtypes.Log
+	// processed are the hashes of the last processed blocks (when known).
+	processed map[uint64]common.Hash
+}
+
+func NewLogFollower(client LogSource, q ethereum.FilterQuery) *LogFollower {
+	return &LogFollower{
+		client:    client,
+		Query:     q,
+		Window:    NewLogWindow(0),
+		Watcher:   NewHeadWatcher(client),
+		recent:    map[common.Hash][]ethtypes.Log{},
+		processed: map[uint64]common.Hash{},
+	}
+}
+
+// Follow handles the logs from the from block, and follows the new blocks until the context is cancelled.
+func (f *LogFollower) Follow(ctx context.Context, from uint64, handler LogHandler) error {
+	head, err := f.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "Couldn't get head block")
+	}
+	if from <= head.Number.Uint64() {
+		err = f.catchUp(ctx, from, head.Number.Uint64(), handler)
+		if err != nil {
+			return err
+		}
+	}
+	next := head.Number.Uint64() + 1
+	if from > next {
+		next = from
+	}
+	next, err = f.register(ctx, head, next, handler)
+	if err != nil {
+		return err
+	}
+
+	events := make(chan HeadEvent)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- f.Watcher.Watch(ctx, events)
+	}()
+	for {
+		select {
+		case e := <-events:
+			next, err = f.processHead(ctx, e, next, handler)
+			if err != nil {
+				return err
+			}
+		case err := <-done:
+			return err
+		}
+	}
+}
+
+// processHead handles one new head event and returns the next expected block.
+func (f *LogFollower) processHead(ctx context.Context, e HeadEvent, next uint64, handler LogHandler) (uint64, error) {
+	number := e.Header.Number.Uint64()
+	if len(e.Removed) > 0 {
+		err := handler(f.removeFrom(number), number-1, e.Header.ParentHash)
+		if err != nil {
+			return next, err
+		}
+		next = number
+	}
+	if number < next {
+		return next, nil
+	}
+	if number > next {
+		// gap which is not filled by the watcher
+		err := f.catchUp(ctx, next, number-1, handler)
+		if err != nil {
+			return next, err
+		}
+	}
+	hash := e.Header.Hash()
+	q := f.Query
+	q.FromBlock = nil
+	q.ToBlock = nil
+	q.BlockHash = &hash
+	logs, err := f.client.FilterLogs(ctx, q)
+	if err != nil {
+		return next, errors.Wrapf(err, "Couldn't get logs of block %d", number)
+	}
+	f.remember(logs, number)
+	f.processed[number] = hash
+	err = handler(logs, number, hash)
+	if err != nil {
+		return next, err
+	}
+	return number + 1, nil
+}
+
+// register passes the last blocks (up to the head) to the watcher, to detect reorgs deeper than the head. Logs of
+// the catch-up which are not part of the canonical chain (reorg during the catch-up) are handled as removed and
+// downloaded again.
+func (f *LogFollower) register(ctx context.Context, head *ethtypes.Header, next uint64, handler LogHandler) (uint64, error) {
+	number := head.Number.Uint64()
+	first := uint64(0)
+	if number >= f.Watcher.Depth {
+		first = number - f.Watcher.Depth + 1
+	}
+	for n := first; n <= number; n++ {
+		header := head
+		if n < number {
+			var err error
+			header, err = f.client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+			if err != nil {
+				return next, errors.Wrapf(err, "Couldn't get block %d", n)
+			}
+		}
+		if f.orphaned(header) {
+			err := handler(f.removeFrom(n), n-1, header.ParentHash)
+			if err != nil {
+				return next, err
+			}
+			if n < next {
+				next = n
+			}
+		}
+		events, err := f.Watcher.Process(ctx, header)
+		if err != nil {
+			return next, err
+		}
+		for _, e := range events {
+			next, err = f.processHead(ctx, e, next, handler)
+			if err != nil {
+				return next, err
+			}
+		}
+		if n < next {
+			f.processed[n] = header.Hash()
+		}
+	}
+	return next, nil
+}
+
+// orphaned returns true if logs of an other block (with the same number) are already handled.
+func (f *LogFollower) orphaned(header *ethtypes.Header) bool {
+	for hash, logs := range f.recent {
+		if len(logs) > 0 && logs[0].BlockNumber == header.Number.Uint64() && hash != header.Hash() {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFrom returns the handled logs from the block (newest first) marked as removed, and forgets them.
+func (f *LogFollower) removeFrom(number uint64) []ethtypes.Log {
+	var removed []ethtypes.Log
+	for hash, logs := range f.recent {
+		if len(logs) > 0 && logs[0].BlockNumber >= number {
+			removed = append(removed, logs...)
+			delete(f.recent, hash)
+		}
+	}
+	for n := range f.processed {
+		if n >= number {
+			delete(f.processed, n)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		if removed[i].BlockNumber != removed[j].BlockNumber {
+			return removed[i].BlockNumber > removed[j].BlockNumber
+		}
+		return removed[i].Index > removed[j].Index
+	})
+	for i := range removed {
+		removed[i].Removed = true
+	}
+	return removed
+}
+
+// Processed returns the hashes of the last processed blocks (which can be saved to detect reorgs after restart).
+func (f *LogFollower) Processed() map[uint64]common.Hash {
+	res := map[uint64]common.Hash{}
+	for n, hash := range f.processed {
+		res[n] = hash
+	}
+	return res
+}
+
+// Rewind compares the previously processed blocks (saved with Processed) with the canonical chain, and returns the
+// first block to process: the one after the common ancestor. Logs of the orphaned blocks are handled as removed (if
+// the node still has them).
+func (f *LogFollower) Rewind(ctx context.Context, processed map[uint64]common.Hash, handler LogHandler) (uint64, error) {
+	var numbers []uint64
+	for n := range processed {
+		numbers = append(numbers, n)
+	}
+	if len(numbers) == 0 {
+		return 0, errors.New("No processed blocks to rewind")
+	}
+	sort.Slice(numbers, func(i, j int) bool {
+		return numbers[i] > numbers[j]
+	})
+
+	var orphans []uint64
+	ancestor := int64(-1)
+	for _, n := range numbers {
+		header, err := f.client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil && !errors.Is(err, ethereum.NotFound) {
+			return 0, errors.Wrapf(err, "Couldn't get block %d", n)
+		}
+		if header != nil && header.Hash() == processed[n] {
+			ancestor = int64(n)
+			break
+		}
+		orphans = append(orphans, n)
+	}
+	if len(orphans) == 0 {
+		for n, hash := range processed {
+			f.processed[n] = hash
+		}
+		return numbers[0] + 1, nil
+	}
+	if ancestor == -1 {
+		ancestor = int64(numbers[len(numbers)-1]) - 1
+		if f.Watcher.Depth < numbers[len(numbers)-1] {
+			ancestor = int64(numbers[len(numbers)-1] - f.Watcher.Depth)
+		}
+		log.Warn().Int64("block", ancestor).Msg("Reorg is deeper than the saved blocks, restarting from an earlier block")
+	}
+
+	var removed []ethtypes.Log
+	for _, n := range orphans {
+		hash := processed[n]
+		q := f.Query
+		q.FromBlock = nil
+		q.ToBlock = nil
+		q.BlockHash = &hash
+		logs, err := f.client.FilterLogs(ctx, q)
+		if err != nil {
+			log.Warn().Err(err).Uint64("block", n).Msg("Logs of the orphaned block are not available")
+			continue
+		}
+		for i := len(logs) - 1; i >= 0; i-- {
+			l := logs[i]
+			l.Removed = true
+			removed = append(removed, l)
+		}
+	}
+	for n, hash := range processed {
+		if int64(n) <= ancestor {
+			f.processed[n] = hash
+		}
+	}
+	var ancestorHash common.Hash
+	if ancestor >= 0 {
+		ancestorHash = processed[uint64(ancestor)]
+	}
+	next := uint64(ancestor + 1)
+	err := handler(removed, next-1, ancestorHash)
+	if err != nil {
+		return 0, err
+	}
+	return next, nil
+}
+
+// catchUp downloads the logs of the block range (inclusive).
+func (f *LogFollower) catchUp(ctx context.Context, from uint64, to uint64, handler LogHandler) error {
+	return f.Window.Fetch(ctx, f.client, f.Query, from, to, func(logs []ethtypes.Log, start uint64, end uint64) error {
+		f.remember(logs, end)
+		return handler(logs, end, common.Hash{})
+	})
+}
+
+// remember saves the logs of the last blocks (which can be removed by reorg).
+func (f *LogFollower) remember(logs []ethtypes.Log, head uint64) {
+	for _, l := range logs {
+		if l.BlockNumber+f.Watcher.Depth >= head {
+			f.recent[l.BlockHash] = append(f.recent[l.BlockHash], l)
+		}
+	}
+	for hash, blockLogs := range f.recent {
+		if len(blockLogs) > 0 && blockLogs[0].BlockNumber+f.Watcher.Depth < head {
+			delete(f.recent, hash)
+		}
+	}
+	for n := range f.processed {
+		if n+f.Watcher.Depth < head {
+			delete(f.processed, n)
+		}
+	}
+}
+
+// BlockAtTime returns the first block which is created at (or after) the time.
+func BlockAtTime(ctx context.Context, client HeadSource, at time.Time) (uint64, error) {
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	target := uint64(at.Unix())
+	if head.Time < target {
+		return head.Number.Uint64() + 1, nil
+	}
+	var searchErr error
+	n := sort.Search(int(head.Number.Uint64()+1), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+		header, err := client.HeaderByNumber(ctx, big.NewInt(int64(i)))
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return header.Time >= target
+	})
+	if searchErr != nil {
+		return 0, searchErr
+	}
+	return uint64(n), nil
+}
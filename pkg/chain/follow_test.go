@@ -0,0 +1,211 @@
+package chain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// fakeLogChain is a fakeChain where each block has one log entry.
+type fakeLogChain struct {
+	*fakeChain
+	byNumber map[uint64]*ethtypes.Header
+}
+
+func (f *fakeLogChain) add(parent *ethtypes.Header, fork uint64) *ethtypes.Header {
+	h := f.fakeChain.add(parent, fork)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.byNumber[h.Number.Uint64()] = h
+	return h
+}
+
+func (f *fakeLogChain) HeaderByNumber(ctx context.Context, number *big.Int) (*ethtypes.Header, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if number == nil {
+		return f.head, nil
+	}
+	return f.byNumber[number.Uint64()], nil
+}
+
+func (f *fakeLogChain) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethtypes.Log, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	logOf := func(h *ethtypes.Header) ethtypes.Log {
+		return ethtypes.Log{BlockNumber: h.Number.Uint64(), BlockHash: h.Hash()}
+	}
+	if q.BlockHash != nil {
+		return []ethtypes.Log{logOf(f.headers[*q.BlockHash])}, nil
+	}
+	var res []ethtypes.Log
+	for n := q.FromBlock.Uint64(); n <= q.ToBlock.Uint64(); n++ {
+		res = append(res, logOf(f.byNumber[n]))
+	}
+	return res, nil
+}
+
+func TestLogFollower(t *testing.T) {
+	f := &fakeLogChain{fakeChain: newFakeChain(), byNumber: map[uint64]*ethtypes.Header{}}
+	parent := f.add(nil, 0)
+	for i := 0; i < 10; i++ {
+		parent = f.add(parent, 0)
+	}
+
+	follower := NewLogFollower(f, ethereum.FilterQuery{})
+	follower.Window = &LogWindow{Size: 3, Min: 1, Max: 3}
+	follower.Watcher.PollInterval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type handled struct {
+		blocks    []uint64
+		removed   bool
+		processed uint64
+	}
+	results := make(chan handled)
+	done := make(chan error)
+	go func() {
+		done <- follower.Follow(ctx, 5, func(logs []ethtypes.Log, processed uint64, hash common.Hash) error {
+			h := handled{processed: processed}
+			for _, l := range logs {
+				h.blocks = append(h.blocks, l.BlockNumber)
+				h.removed = l.Removed
+			}
+			results <- h
+			return nil
+		})
+	}()
+
+	// catch-up
+	require.Equal(t, handled{blocks: []uint64{5, 6, 7}, processed: 7}, <-results)
+	require.Equal(t, handled{blocks: []uint64{8, 9, 10}, processed: 10}, <-results)
+
+	// replace the head (10) with a new branch
+	block9, err := f.HeaderByNumber(ctx, big.NewInt(9))
+	require.NoError(t, err)
+	fork := f.add(block9, 1)
+	require.Equal(t, handled{blocks: []uint64{10}, removed: true, processed: 9}, <-results)
+	require.Equal(t, handled{blocks: []uint64{10}, processed: 10}, <-results)
+
+	f.add(fork, 1)
+	require.Equal(t, handled{blocks: []uint64{11}, processed: 11}, <-results)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestLogFollowerCatchUpReorg(t *testing.T) {
+	f := &fakeLogChain{fakeChain: newFakeChain(), byNumber: map[uint64]*ethtypes.Header{}}
+	parent := f.add(nil, 0)
+	for i := 0; i < 10; i++ {
+		parent = f.add(parent, 0)
+	}
+
+	follower := NewLogFollower(f, ethereum.FilterQuery{})
+	follower.Window = &LogWindow{Size: 3, Min: 1, Max: 3}
+	follower.Watcher.PollInterval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := make(chan []ethtypes.Log)
+	done := make(chan error)
+	go func() {
+		done <- follower.Follow(ctx, 5, func(logs []ethtypes.Log, processed uint64, hash common.Hash) error {
+			results <- logs
+			return nil
+		})
+	}()
+	blocks := func(logs []ethtypes.Log) (res []uint64) {
+		for _, l := range logs {
+			res = append(res, l.BlockNumber)
+		}
+		return res
+	}
+
+	handled := map[uint64]common.Hash{}
+	for _, expected := range [][]uint64{{5, 6, 7}, {8, 9, 10}} {
+		logs := <-results
+		require.Equal(t, expected, blocks(logs))
+		for _, l := range logs {
+			handled[l.BlockNumber] = l.BlockHash
+		}
+	}
+
+	// replace the blocks of the catch-up below the head
+	block7, err := f.HeaderByNumber(ctx, big.NewInt(7))
+	require.NoError(t, err)
+	branch := []*ethtypes.Header{f.add(block7, 1)}
+	for i := 0; i < 3; i++ {
+		branch = append(branch, f.add(branch[len(branch)-1], 1))
+	}
+
+	// the reorg can be detected in multiple steps (depending on the time of the polling), but the handled logs
+	// should follow the new branch at the end
+	for handled[11] != branch[3].Hash() {
+		for _, l := range <-results {
+			if l.Removed {
+				require.Equal(t, handled[l.BlockNumber], l.BlockHash)
+				delete(handled, l.BlockNumber)
+			} else {
+				require.NotContains(t, handled, l.BlockNumber)
+				handled[l.BlockNumber] = l.BlockHash
+			}
+		}
+	}
+	for i, h := range branch {
+		require.Equal(t, h.Hash(), handled[uint64(8+i)])
+	}
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestLogFollowerRewind(t *testing.T) {
+	ctx := context.Background()
+	f := &fakeLogChain{fakeChain: newFakeChain(), byNumber: map[uint64]*ethtypes.Header{}}
+	parent := f.add(nil, 0)
+	for i := 0; i < 10; i++ {
+		parent = f.add(parent, 0)
+	}
+	processed := map[uint64]common.Hash{}
+	for n := uint64(7); n <= 10; n++ {
+		processed[n] = f.byNumber[n].Hash()
+	}
+
+	// checkpoint is still canonical
+	follower := NewLogFollower(f, ethereum.FilterQuery{})
+	next, err := follower.Rewind(ctx, processed, func(logs []ethtypes.Log, processed uint64, hash common.Hash) error {
+		t.Fatal("handler shouldn't be called without reorg")
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(11), next)
+
+	// blocks 9 and 10 are replaced while the follower was stopped
+	fork := f.add(f.byNumber[8], 1)
+	f.add(fork, 1)
+
+	follower = NewLogFollower(f, ethereum.FilterQuery{})
+	var removed []ethtypes.Log
+	next, err = follower.Rewind(ctx, processed, func(logs []ethtypes.Log, p uint64, hash common.Hash) error {
+		require.Equal(t, uint64(8), p)
+		require.Equal(t, processed[8], hash)
+		removed = logs
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(9), next)
+	require.Len(t, removed, 2)
+	require.Equal(t, processed[10], removed[0].BlockHash)
+	require.Equal(t, processed[9], removed[1].BlockHash)
+	require.True(t, removed[0].Removed)
+	require.Equal(t, map[uint64]common.Hash{7: processed[7], 8: processed[8]}, follower.Processed())
+}
@@ -0,0 +1,200 @@
+package chain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"math/big"
+	"time"
+)
+
+// HeadSource is the part of the ethclient.Client which is required to follow the chain head.
+type HeadSource interface {
+	SubscribeNewHead(ctx context.Context, ch chan<- *ethtypes.Header) (ethereum.Subscription, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*ethtypes.Header, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*ethtypes.Header, error)
+}
+
+// HeadEvent is a new block of the canonical chain.
+type HeadEvent struct {
+	Header *ethtypes.Header
+	// Removed contains the hashes of the blocks which are not part of the canonical chain any more (reorg), starting
+	// with the oldest one.
+	Removed []common.Hash
+}
+
+// HeadWatcher follows the chain head with subscription (or with polling, if subscription is not supported), and
+// reports the blocks in order, together with the reorgs.
+type HeadWatcher struct {
+	client HeadSource
+	// PollInterval is the frequency of eth_blockNumber polling, if subscription is not available.
+	PollInterval time.Duration
+	// ReconnectDelay is the wait time before re-subscribing after subscription error.
+	ReconnectDelay time.Duration
+	// Depth is the number of the remembered blocks (deeper reorgs are not detected).
+	Depth uint64
+
+	last  *ethtypes.Header
+	known map[uint64]common.Hash
+}
+
+func NewHeadWatcher(client HeadSource) *HeadWatcher {
+	return &HeadWatcher{
+		client:         client,
+		PollInterval:   2 * time.Second,
+		ReconnectDelay: 5 * time.Second,
+		Depth:          64,
+		known:          map[uint64]common.Hash{},
+	}
+}
+
+// Watch sends the new heads to the channel until the context is cancelled.
+func (w *HeadWatcher) Watch(ctx context.Context, events chan<- HeadEvent) error {
+	for {
+		err := w.subscribe(ctx, events)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+			log.Debug().Msg("Subscription is not supported, polling for new blocks")
+			return w.poll(ctx, events)
+		}
+		log.Warn().Err(err).Msg("Head subscription is failed, reconnecting")
+		select {
+		case <-time.After(w.ReconnectDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (w *HeadWatcher) subscribe(ctx context.Context, events chan<- HeadEvent) error {
+	ch := make(chan *ethtypes.Header)
+	subscription, err := w.client.SubscribeNewHead(ctx, ch)
+	if err != nil {
+		return err
+	}
+	defer subscription.Unsubscribe()
+	for {
+		select {
+		case header := <-ch:
+			err = w.emit(ctx, header, events)
+			if err != nil {
+				return err
+			}
+		case err := <-subscription.Err():
+			if err == nil {
+				err = errors.New("subscription is closed")
+			}
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (w *HeadWatcher) poll(ctx context.Context, events chan<- HeadEvent) error {
+	for {
+		header, err := w.client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Warn().Err(err).Msg("Couldn't get the head block")
+		} else {
+			err = w.emit(ctx, header, events)
+			if err != nil {
+				return err
+			}
+		}
+		select {
+		case <-time.After(w.PollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (w *HeadWatcher) emit(ctx context.Context, header *ethtypes.Header, events chan<- HeadEvent) error {
+	newEvents, err := w.Process(ctx, header)
+	if err != nil {
+		return err
+	}
+	for _, e := range newEvents {
+		select {
+		case events <- e:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Process registers a new head, and returns the new blocks of the canonical chain. Missing blocks (between the last
+// and the new head) are downloaded. Reorg is detected if the new block is not a descendant of the last seen head.
+func (w *HeadWatcher) Process(ctx context.Context, header *ethtypes.Header) ([]HeadEvent, error) {
+	number := header.Number.Uint64()
+	if hash, found := w.known[number]; found && hash == header.Hash() {
+		return nil, nil
+	}
+	if w.last == nil {
+		w.register(header)
+		return []HeadEvent{{Header: header}}, nil
+	}
+	lastNumber := w.last.Number.Uint64()
+
+	// walk back to the last known block of the new branch
+	branch := []*ethtypes.Header{header}
+	current := header
+	for current.Number.Uint64() > 0 && uint64(len(branch)) <= w.Depth {
+		parentNumber := current.Number.Uint64() - 1
+		if hash, found := w.known[parentNumber]; found && hash == current.ParentHash {
+			break
+		}
+		if _, found := w.known[parentNumber]; !found && parentNumber <= lastNumber {
+			// older than the remembered history
+			break
+		}
+		parent, err := w.client.HeaderByHash(ctx, current.ParentHash)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Couldn't get parent block %s", current.ParentHash)
+		}
+		branch = append([]*ethtypes.Header{parent}, branch...)
+		current = parent
+	}
+
+	ancestor := branch[0].Number.Uint64() - 1
+	var removed []common.Hash
+	for n := ancestor + 1; n <= lastNumber; n++ {
+		if hash, found := w.known[n]; found {
+			removed = append(removed, hash)
+			delete(w.known, n)
+		}
+	}
+
+	var res []HeadEvent
+	for ix, h := range branch {
+		e := HeadEvent{Header: h}
+		if ix == 0 {
+			e.Removed = removed
+		}
+		w.register(h)
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+func (w *HeadWatcher) register(header *ethtypes.Header) {
+	number := header.Number.Uint64()
+	w.known[number] = header.Hash()
+	w.last = header
+	for n := range w.known {
+		if n+w.Depth < number || n > number {
+			delete(w.known, n)
+		}
+	}
+}
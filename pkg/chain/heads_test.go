@@ -0,0 +1,134 @@
+package chain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeChain is an in-memory HeadSource without subscription support.
+type fakeChain struct {
+	mu      sync.Mutex
+	headers map[common.Hash]*ethtypes.Header
+	head    *ethtypes.Header
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{
+		headers: map[common.Hash]*ethtypes.Header{},
+	}
+}
+
+// add creates a new block on top of the parent (fork is used to create different hash for the same height).
+func (f *fakeChain) add(parent *ethtypes.Header, fork uint64) *ethtypes.Header {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	h := &ethtypes.Header{
+		Number:     big.NewInt(0),
+		Difficulty: big.NewInt(0),
+		Nonce:      ethtypes.EncodeNonce(fork),
+	}
+	if parent != nil {
+		h.Number = new(big.Int).Add(parent.Number, big.NewInt(1))
+		h.ParentHash = parent.Hash()
+	}
+	f.headers[h.Hash()] = h
+	f.head = h
+	return h
+}
+
+func (f *fakeChain) SubscribeNewHead(ctx context.Context, ch chan<- *ethtypes.Header) (ethereum.Subscription, error) {
+	return nil, rpc.ErrNotificationsUnsupported
+}
+
+func (f *fakeChain) HeaderByNumber(ctx context.Context, number *big.Int) (*ethtypes.Header, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.head, nil
+}
+
+func (f *fakeChain) HeaderByHash(ctx context.Context, hash common.Hash) (*ethtypes.Header, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	h, found := f.headers[hash]
+	if !found {
+		return nil, ethereum.NotFound
+	}
+	return h, nil
+}
+
+func numbers(events []HeadEvent) []uint64 {
+	var res []uint64
+	for _, e := range events {
+		res = append(res, e.Header.Number.Uint64())
+	}
+	return res
+}
+
+func TestHeadWatcherReorg(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeChain()
+	b0 := f.add(nil, 0)
+	b1 := f.add(b0, 0)
+	b2 := f.add(b1, 0)
+	b3 := f.add(b2, 0)
+
+	w := NewHeadWatcher(f)
+	events, err := w.Process(ctx, b1)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, numbers(events))
+
+	// missing block is downloaded
+	events, err = w.Process(ctx, b3)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2, 3}, numbers(events))
+	require.Empty(t, events[0].Removed)
+
+	// duplicate
+	events, err = w.Process(ctx, b3)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	// reorg: 3 and 2 are replaced
+	fork2 := f.add(b1, 1)
+	fork3 := f.add(fork2, 1)
+	fork4 := f.add(fork3, 1)
+	events, err = w.Process(ctx, fork4)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2, 3, 4}, numbers(events))
+	require.Equal(t, []common.Hash{b2.Hash(), b3.Hash()}, events[0].Removed)
+	require.Equal(t, fork2.Hash(), events[0].Header.Hash())
+
+	// reorg to a shorter chain
+	other4 := f.add(fork3, 2)
+	events, err = w.Process(ctx, other4)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{4}, numbers(events))
+	require.Equal(t, []common.Hash{fork4.Hash()}, events[0].Removed)
+}
+
+func TestHeadWatcherPolling(t *testing.T) {
+	f := newFakeChain()
+	b0 := f.add(nil, 0)
+	f.add(b0, 0)
+
+	w := NewHeadWatcher(f)
+	w.PollInterval = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan HeadEvent)
+	done := make(chan error)
+	go func() {
+		done <- w.Watch(ctx, events)
+	}()
+	require.Equal(t, uint64(1), (<-events).Header.Number.Uint64())
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
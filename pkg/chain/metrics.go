@@ -0,0 +1,149 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/rpc"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RequestMetrics collects the JSON-RPC requests of all the clients created by DialRPC.
+var RequestMetrics = NewMetrics()
+
+// MethodStat is the summary of the requests of one JSON-RPC method.
+type MethodStat struct {
+	Method string
+	// Calls is the number of the method calls (one batch may contain many calls).
+	Calls int
+	// Requests is the number of HTTP round-trips which included the method.
+	Requests int
+	Errors   int
+	Total    time.Duration
+	Max      time.Duration
+}
+
+// Metrics counts the JSON-RPC calls and latency per method.
+type Metrics struct {
+	mu         sync.Mutex
+	methods    map[string]*MethodStat
+	roundTrips int
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		methods: map[string]*MethodStat{},
+	}
+}
+
+func (m *Metrics) record(methods []string, latency time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.roundTrips++
+	seen := map[string]bool{}
+	for _, method := range methods {
+		stat, found := m.methods[method]
+		if !found {
+			stat = &MethodStat{Method: method}
+			m.methods[method] = stat
+		}
+		stat.Calls++
+		if seen[method] {
+			continue
+		}
+		seen[method] = true
+		stat.Requests++
+		stat.Total += latency
+		if latency > stat.Max {
+			stat.Max = latency
+		}
+		if failed {
+			stat.Errors++
+		}
+	}
+}
+
+// RoundTrips returns the number of HTTP requests (one batch is one round-trip).
+func (m *Metrics) RoundTrips() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.roundTrips
+}
+
+// Stats returns the collected statistics ordered by method name.
+func (m *Metrics) Stats() []MethodStat {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []MethodStat
+	for _, s := range m.methods {
+		res = append(res, *s)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Method < res[j].Method
+	})
+	return res
+}
+
+// meteredTransport is a http.RoundTripper which records the methods of the JSON-RPC requests.
+type meteredTransport struct {
+	next    http.RoundTripper
+	metrics *Metrics
+}
+
+func (t *meteredTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var methods []string
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		methods = requestMethods(body)
+	}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	t.metrics.record(methods, time.Since(start), err != nil || (resp != nil && resp.StatusCode >= 300))
+	return resp, err
+}
+
+// requestMethods returns the method names of a single or batch JSON-RPC request.
+func requestMethods(body []byte) []string {
+	type request struct {
+		Method string `json:"method"`
+	}
+	trimmed := bytes.TrimSpace(body)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		var batch []request
+		if err := json.Unmarshal(trimmed, &batch); err != nil {
+			return []string{"<invalid>"}
+		}
+		var methods []string
+		for _, r := range batch {
+			methods = append(methods, r.Method)
+		}
+		return methods
+	}
+	var r request
+	if err := json.Unmarshal(trimmed, &r); err != nil {
+		return []string{"<invalid>"}
+	}
+	return []string{r.Method}
+}
+
+// DialRPC creates the RPC client. Requests of HTTP endpoints are recorded in RequestMetrics.
+func DialRPC(ctx context.Context, url string) (*rpc.Client, error) {
+	if isHTTP(url) {
+		return rpc.DialHTTPWithClient(url, &http.Client{
+			Transport: &meteredTransport{
+				next:    http.DefaultTransport,
+				metrics: RequestMetrics,
+			},
+		})
+	}
+	return rpc.DialContext(ctx, url)
+}
@@ -0,0 +1,234 @@
+package chain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"math/big"
+	"strings"
+)
+
+// Multicall3Address is the address of the Multicall3 contract, which is deployed to the same address on most of the
+// chains.
+var Multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+const multicall3Abi = `[{"inputs":[{"components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}],"name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}],"name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+
+// DefaultBatchSize is the maximum number of calls sent in one aggregate3 call (or JSON-RPC batch).
+const DefaultBatchSize = 500
+
+// ContractCaller can execute read-only calls (implemented by ethclient.Client and the simulated backend).
+type ContractCaller interface {
+	CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error)
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// BatchCaller can send multiple JSON-RPC requests at once (implemented by rpc.Client).
+type BatchCaller interface {
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
+
+// QueryCall is one read-only call of a batch.
+type QueryCall struct {
+	Target common.Address
+	Data   []byte
+}
+
+// QueryResult is the raw response of one call. Failed calls have Err (instead of stopping the whole batch).
+type QueryResult struct {
+	Data []byte
+	Err  error
+}
+
+// Multicall executes many read-only calls with a few requests: with aggregate3 calls if the Multicall3 contract is
+// deployed, or with JSON-RPC batch requests otherwise.
+type Multicall struct {
+	caller    ContractCaller
+	batch     BatchCaller
+	abi       abi.ABI
+	deployed  *bool
+	Address   common.Address
+	From      common.Address
+	BatchSize int
+}
+
+// NewMulticall creates the batching layer. The batch caller is optional: without it, calls are sent one by one when
+// the multicall contract is missing.
+func NewMulticall(caller ContractCaller, batch BatchCaller) *Multicall {
+	parsed, err := abi.JSON(strings.NewReader(multicall3Abi))
+	if err != nil {
+		panic(err)
+	}
+	return &Multicall{
+		caller:    caller,
+		batch:     batch,
+		abi:       parsed,
+		Address:   Multicall3Address,
+		BatchSize: DefaultBatchSize,
+	}
+}
+
+// Query executes all the calls and returns the results in the same order.
+func (m *Multicall) Query(ctx context.Context, calls []QueryCall) ([]QueryResult, error) {
+	deployed, err := m.isDeployed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize := m.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	var results []QueryResult
+	for start := 0; start < len(calls); start += batchSize {
+		end := start + batchSize
+		if end > len(calls) {
+			end = len(calls)
+		}
+		var chunk []QueryResult
+		switch {
+		case deployed:
+			chunk, err = m.aggregate3(ctx, calls[start:end])
+		case m.batch != nil:
+			chunk, err = m.rpcBatch(ctx, calls[start:end])
+		default:
+			chunk, err = m.sequential(ctx, calls[start:end])
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, chunk...)
+	}
+	return results, nil
+}
+
+func (m *Multicall) isDeployed(ctx context.Context) (bool, error) {
+	if m.deployed == nil {
+		code, err := m.caller.CodeAt(ctx, m.Address, nil)
+		if err != nil {
+			return false, errors.Wrap(err, "Couldn't check multicall contract")
+		}
+		deployed := len(code) > 0
+		m.deployed = &deployed
+		log.Debug().Bool("deployed", deployed).Str("address", m.Address.Hex()).Msg("Multicall3")
+	}
+	return *m.deployed, nil
+}
+
+type multicall3Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type multicall3Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+func (m *Multicall) aggregate3(ctx context.Context, calls []QueryCall) ([]QueryResult, error) {
+	var args []multicall3Call
+	for _, c := range calls {
+		args = append(args, multicall3Call{
+			Target:       c.Target,
+			AllowFailure: true,
+			CallData:     c.Data,
+		})
+	}
+	data, err := m.abi.Pack("aggregate3", args)
+	if err != nil {
+		return nil, errors.Wrap(err, "Couldn't encode aggregate3 call")
+	}
+	res, err := m.caller.CallContract(ctx, ethereum.CallMsg{
+		From: m.From,
+		To:   &m.Address,
+		Data: data,
+	}, nil)
+	if err != nil {
+		return nil, errors.Wrap(NewRevertError(err), "aggregate3 call is failed")
+	}
+
+	var returned []multicall3Result
+	err = m.abi.UnpackIntoInterface(&returned, "aggregate3", res)
+	if err != nil {
+		return nil, errors.Wrap(err, "Couldn't decode aggregate3 response")
+	}
+	if len(returned) != len(calls) {
+		return nil, errors.Errorf("aggregate3 returned %d results instead of %d", len(returned), len(calls))
+	}
+
+	var results []QueryResult
+	for _, r := range returned {
+		if r.Success {
+			results = append(results, QueryResult{Data: r.ReturnData})
+		} else {
+			results = append(results, QueryResult{Err: revertedCall(r.ReturnData)})
+		}
+	}
+	return results, nil
+}
+
+func (m *Multicall) rpcBatch(ctx context.Context, calls []QueryCall) ([]QueryResult, error) {
+	elems := make([]rpc.BatchElem, len(calls))
+	responses := make([]hexutil.Bytes, len(calls))
+	for ix, c := range calls {
+		elems[ix] = rpc.BatchElem{
+			Method: "eth_call",
+			Args: []interface{}{
+				map[string]interface{}{
+					"from": m.From,
+					"to":   c.Target,
+					"data": hexutil.Bytes(c.Data),
+				},
+				"latest",
+			},
+			Result: &responses[ix],
+		}
+	}
+	err := m.batch.BatchCallContext(ctx, elems)
+	if err != nil {
+		return nil, errors.Wrap(err, "JSON-RPC batch request is failed")
+	}
+	var results []QueryResult
+	for ix, e := range elems {
+		if e.Error != nil {
+			results = append(results, QueryResult{Err: NewRevertError(e.Error)})
+		} else {
+			results = append(results, QueryResult{Data: responses[ix]})
+		}
+	}
+	return results, nil
+}
+
+func (m *Multicall) sequential(ctx context.Context, calls []QueryCall) ([]QueryResult, error) {
+	var results []QueryResult
+	for _, c := range calls {
+		target := c.Target
+		res, err := m.caller.CallContract(ctx, ethereum.CallMsg{
+			From: m.From,
+			To:   &target,
+			Data: c.Data,
+		}, nil)
+		if err != nil {
+			results = append(results, QueryResult{Err: NewRevertError(err)})
+		} else {
+			results = append(results, QueryResult{Data: res})
+		}
+	}
+	return results, nil
+}
+
+// revertedCall creates the error of a failed call of aggregate3.
+func revertedCall(data []byte) error {
+	err := errors.New("execution reverted")
+	if len(data) == 0 {
+		return err
+	}
+	return newRevertError(data, err)
+}
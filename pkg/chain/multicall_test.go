@@ -0,0 +1,126 @@
+package chain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+var (
+	// echo returns the call data
+	echoCode = hexutil.MustDecode("0x366000600037366000f3")
+	// revert reverts with Error("no")
+	revertCode = hexutil.MustDecode("0x" +
+		"6308c379a060e01b600052" + // selector
+		"6020600452" + // offset of the string
+		"6002602452" + // length of the string
+		"7f6e6f" + strings.Repeat("00", 30) + "604452" + // "no"
+		"60646000fd")
+	echoAddress   = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	revertAddress = common.HexToAddress("0x1000000000000000000000000000000000000002")
+)
+
+func multicallCalls() []QueryCall {
+	var calls []QueryCall
+	for i := 0; i < 5; i++ {
+		calls = append(calls, QueryCall{Target: echoAddress, Data: []byte{byte(i), 1, 2, 3}})
+	}
+	calls = append(calls, QueryCall{Target: revertAddress, Data: []byte{1}})
+	return calls
+}
+
+func requireMulticallResults(t *testing.T, results []QueryResult) {
+	require.Len(t, results, 6)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, results[i].Err)
+		require.Equal(t, []byte{byte(i), 1, 2, 3}, results[i].Data)
+	}
+	require.Error(t, results[5].Err)
+	require.Equal(t, "execution reverted: no", results[5].Err.Error())
+}
+
+func simulatedBackend(t *testing.T, withMulticall bool) *backends.SimulatedBackend {
+	alloc := core.GenesisAlloc{
+		echoAddress:   {Code: echoCode, Balance: big.NewInt(0)},
+		revertAddress: {Code: revertCode, Balance: big.NewInt(0)},
+	}
+	if withMulticall {
+		content, err := ioutil.ReadFile("testdata/multicall3.bin")
+		require.NoError(t, err)
+		alloc[Multicall3Address] = core.GenesisAccount{Code: common.FromHex(strings.TrimSpace(string(content))), Balance: big.NewInt(0)}
+	}
+	backend := backends.NewSimulatedBackend(alloc, 30_000_000)
+	t.Cleanup(func() {
+		_ = backend.Close()
+	})
+	return backend
+}
+
+func TestMulticallAggregate3(t *testing.T) {
+	m := NewMulticall(simulatedBackend(t, true), nil)
+	m.BatchSize = 4
+
+	results, err := m.Query(context.Background(), multicallCalls())
+	require.NoError(t, err)
+	require.True(t, *m.deployed)
+	requireMulticallResults(t, results)
+}
+
+func TestMulticallSequential(t *testing.T) {
+	m := NewMulticall(simulatedBackend(t, false), nil)
+
+	results, err := m.Query(context.Background(), multicallCalls())
+	require.NoError(t, err)
+	require.False(t, *m.deployed)
+	requireMulticallResults(t, results)
+}
+
+// batchStub serves eth_call and eth_getCode from the simulated backend to test JSON-RPC batch requests.
+type batchStub struct {
+	backend *backends.SimulatedBackend
+	calls   int
+}
+
+func (s *batchStub) GetCode(address common.Address, block string) (hexutil.Bytes, error) {
+	return s.backend.CodeAt(context.Background(), address, nil)
+}
+
+func (s *batchStub) Call(args struct {
+	From common.Address
+	To   common.Address
+	Data hexutil.Bytes
+}, block string) (hexutil.Bytes, error) {
+	s.calls++
+	res, err := NewMulticall(s.backend, nil).sequential(context.Background(), []QueryCall{{Target: args.To, Data: args.Data}})
+	if err != nil {
+		return nil, err
+	}
+	if revertErr, ok := res[0].Err.(*RevertError); ok {
+		return nil, revertStubError{data: revertErr.Data}
+	}
+	return res[0].Data, res[0].Err
+}
+
+func TestMulticallBatch(t *testing.T) {
+	stub := &batchStub{backend: simulatedBackend(t, false)}
+	server := rpc.NewServer()
+	defer server.Stop()
+	require.NoError(t, server.RegisterName("eth", stub))
+	rpcClient := rpc.DialInProc(server)
+	defer rpcClient.Close()
+
+	m := NewMulticall(ethclient.NewClient(rpcClient), rpcClient)
+	results, err := m.Query(context.Background(), multicallCalls())
+	require.NoError(t, err)
+	require.Equal(t, 6, stub.calls)
+	requireMulticallResults(t, results)
+}
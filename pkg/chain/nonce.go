@@ -0,0 +1,208 @@
+package chain
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NonceManager reserves the nonces of the sent transactions in a local state file (one per chain and account), to
+// make it possible to send transactions in parallel (from multiple processes) without nonce collision.
+type NonceManager struct {
+	Dir string
+	// ReservationTimeout is the time after a reserved (but not used) nonce can be reserved again.
+	ReservationTimeout time.Duration
+	// LockTimeout is the maximum wait time for the lock file.
+	LockTimeout time.Duration
+	// StaleLock is the age of a lock file which is considered to be abandoned by a crashed process.
+	StaleLock time.Duration
+}
+
+// NonceState is the saved state of one account.
+type NonceState struct {
+	// Next is the nonce after the last reserved one.
+	Next uint64 `json:"next"`
+	// Reservations are the reserved nonces (which are not yet seen by the node), with the time of the reservation.
+	Reservations map[uint64]time.Time `json:"reservations,omitempty"`
+}
+
+func NewNonceManager(dir string) *NonceManager {
+	return &NonceManager{
+		Dir:                dir,
+		ReservationTimeout: 10 * time.Minute,
+		LockTimeout:        10 * time.Second,
+		StaleLock:          30 * time.Second,
+	}
+}
+
+func (m *NonceManager) file(chainID int64, account common.Address, ext string) string {
+	return filepath.Join(m.Dir, fmt.Sprintf("%d", chainID), strings.ToLower(account.Hex())+ext)
+}
+
+// Reserve returns the nonce for the next transaction. Pending is the pending nonce of the node: nonces below it are
+// already used. Nonces between the pending nonce and the last reserved one are reused if they are not reserved (or
+// the reservation is expired): they are gaps which would block the later transactions.
+func (m *NonceManager) Reserve(chainID int64, account common.Address, pending uint64) (nonce uint64, err error) {
+	err = m.update(chainID, account, func(state *NonceState) error {
+		state.sync(pending)
+		gaps := state.Gaps(pending, m.ReservationTimeout)
+		if len(gaps) > 0 {
+			nonce = gaps[0]
+			log.Warn().Uint64("nonce", nonce).Uint64("pending", pending).Msg("Nonce gap is detected, reusing nonce")
+		} else {
+			nonce = state.Next
+			state.Next++
+		}
+		state.Reservations[nonce] = time.Now()
+		return nil
+	})
+	return nonce, err
+}
+
+// Release frees the reserved nonce (for example, when the transaction couldn't be sent).
+func (m *NonceManager) Release(chainID int64, account common.Address, nonce uint64) error {
+	return m.update(chainID, account, func(state *NonceState) error {
+		delete(state.Reservations, nonce)
+		if nonce+1 == state.Next {
+			state.Next = nonce
+		}
+		return nil
+	})
+}
+
+// Reset removes the local state of the account (the next reservation starts from the pending nonce of the node).
+func (m *NonceManager) Reset(chainID int64, account common.Address) error {
+	return m.update(chainID, account, func(state *NonceState) error {
+		*state = NonceState{Reservations: map[uint64]time.Time{}}
+		return nil
+	})
+}
+
+// State returns the saved state of the account (empty, if there is no saved state).
+func (m *NonceManager) State(chainID int64, account common.Address) (NonceState, error) {
+	return m.load(chainID, account)
+}
+
+// sync drops the reservations which are already used (below the pending nonce of the node).
+func (s *NonceState) sync(pending uint64) {
+	for nonce := range s.Reservations {
+		if nonce < pending {
+			delete(s.Reservations, nonce)
+		}
+	}
+	if s.Next < pending {
+		s.Next = pending
+	}
+}
+
+// Gaps returns the nonces between the pending nonce of the node and the next nonce which are not reserved (or the
+// reservation is older than the timeout).
+func (s NonceState) Gaps(pending uint64, timeout time.Duration) []uint64 {
+	var gaps []uint64
+	for nonce := pending; nonce < s.Next; nonce++ {
+		reserved, found := s.Reservations[nonce]
+		if !found || time.Since(reserved) > timeout {
+			gaps = append(gaps, nonce)
+		}
+	}
+	return gaps
+}
+
+// Reserved returns the reserved nonces in order.
+func (s NonceState) Reserved() []uint64 {
+	var res []uint64
+	for nonce := range s.Reservations {
+		res = append(res, nonce)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i] < res[j]
+	})
+	return res
+}
+
+// update modifies the state of the account while the lock file is held.
+func (m *NonceManager) update(chainID int64, account common.Address, change func(state *NonceState) error) error {
+	unlock, err := m.lock(chainID, account)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := m.load(chainID, account)
+	if err != nil {
+		return err
+	}
+	err = change(&state)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := m.file(chainID, account, ".json")
+	tmp := file + ".tmp"
+	err = ioutil.WriteFile(tmp, content, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+func (m *NonceManager) load(chainID int64, account common.Address) (NonceState, error) {
+	state := NonceState{}
+	content, err := ioutil.ReadFile(m.file(chainID, account, ".json"))
+	if err != nil && !os.IsNotExist(err) {
+		return state, err
+	}
+	if err == nil {
+		err = json.Unmarshal(content, &state)
+		if err != nil {
+			return state, errors.Wrap(err, "Invalid nonce state file (use 'account nonce --reset' to fix it)")
+		}
+	}
+	if state.Reservations == nil {
+		state.Reservations = map[uint64]time.Time{}
+	}
+	return state, nil
+}
+
+// lock creates the lock file of the account (waits if it's held by an other process).
+func (m *NonceManager) lock(chainID int64, account common.Address) (func(), error) {
+	file := m.file(chainID, account, ".lock")
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(m.LockTimeout)
+	for {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, _ = fmt.Fprintf(f, "%d", os.Getpid())
+			_ = f.Close()
+			return func() {
+				_ = os.Remove(file)
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(file); err == nil && time.Since(info.ModTime()) > m.StaleLock {
+			log.Warn().Str("file", file).Msg("Removing stale nonce lock file")
+			_ = os.Remove(file)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.Errorf("Nonce lock file %s is held by an other process", file)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
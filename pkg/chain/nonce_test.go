@@ -0,0 +1,104 @@
+package chain
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNonceManagerParallel(t *testing.T) {
+	m := NewNonceManager(t.TempDir())
+	account := common.HexToAddress("0x01")
+
+	var lock sync.Mutex
+	var nonces []uint64
+	var errs []error
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// the node doesn't know any of the transactions yet
+			nonce, err := m.Reserve(1, account, 5)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			nonces = append(nonces, nonce)
+		}()
+	}
+	wg.Wait()
+	require.Empty(t, errs)
+
+	sort.Slice(nonces, func(i, j int) bool {
+		return nonces[i] < nonces[j]
+	})
+	require.Equal(t, []uint64{5, 6, 7, 8, 9, 10, 11, 12, 13, 14}, nonces)
+
+	// other chain has independent state
+	nonce, err := m.Reserve(2, account, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), nonce)
+}
+
+func TestNonceManagerGaps(t *testing.T) {
+	m := NewNonceManager(t.TempDir())
+	account := common.HexToAddress("0x01")
+
+	for i := 0; i < 3; i++ {
+		_, err := m.Reserve(1, account, 0)
+		require.NoError(t, err)
+	}
+
+	// failed send
+	require.NoError(t, m.Release(1, account, 1))
+	state, err := m.State(1, account)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, state.Gaps(0, m.ReservationTimeout))
+
+	nonce, err := m.Reserve(1, account, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), nonce)
+
+	// release of the last one doesn't create a gap
+	require.NoError(t, m.Release(1, account, 2))
+	nonce, err = m.Reserve(1, account, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), nonce)
+
+	// expired reservation is reused
+	m.ReservationTimeout = 0
+	time.Sleep(time.Millisecond)
+	nonce, err = m.Reserve(1, account, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), nonce)
+}
+
+func TestNonceManagerSync(t *testing.T) {
+	m := NewNonceManager(t.TempDir())
+	account := common.HexToAddress("0x01")
+
+	nonce, err := m.Reserve(1, account, 3)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), nonce)
+
+	// transactions are sent by an other tool
+	nonce, err = m.Reserve(1, account, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), nonce)
+
+	state, err := m.State(1, account)
+	require.NoError(t, err)
+	require.Equal(t, uint64(11), state.Next)
+	require.Equal(t, []uint64{10}, state.Reserved())
+
+	require.NoError(t, m.Reset(1, account))
+	nonce, err = m.Reserve(1, account, 4)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), nonce)
+}
@@ -0,0 +1,94 @@
+package chain
+
+import (
+	"context"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"math/big"
+)
+
+// MinFeeBump is the minimal fee increase (in percent) of a replacement transaction, which is accepted by the nodes.
+const MinFeeBump = 10
+
+// BumpFee increases the fee with the given percent (rounded up).
+func BumpFee(fee *big.Int, percent int64) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+percent))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// FeeMarket is the current fee level of the chain, used to price the replacement transactions.
+type FeeMarket struct {
+	// BaseFee of the latest block (nil, if the chain doesn't support EIP-1559).
+	BaseFee *big.Int
+	// GasTipCap is the suggested tip (nil, if the chain doesn't support EIP-1559).
+	GasTipCap *big.Int
+	// GasPrice is the suggested gas price of legacy transactions.
+	GasPrice *big.Int
+}
+
+// FeeMarket returns the current base fee and the suggested fees of the node.
+func (c *Eth) FeeMarket(ctx context.Context) (FeeMarket, error) {
+	head, err := c.Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return FeeMarket{}, errors.Wrap(err, "Couldn't get the latest block")
+	}
+	gasPrice, err := c.Client.SuggestGasPrice(ctx)
+	if err != nil {
+		return FeeMarket{}, errors.Wrap(err, "Couldn't get suggested gas price")
+	}
+	m := FeeMarket{
+		BaseFee:  head.BaseFee,
+		GasPrice: gasPrice,
+	}
+	if head.BaseFee != nil {
+		m.GasTipCap, err = c.Client.SuggestGasTipCap(ctx)
+		if err != nil {
+			return FeeMarket{}, errors.Wrap(err, "Couldn't get suggested gas tip")
+		}
+	}
+	return m, nil
+}
+
+// ReplacementFees returns the type and fee options of a transaction which can replace tx (same nonce). Both the tip
+// and the fee cap are bumped with at least MinFeeBump percent, and further increased if the current market requires
+// it (the fee cap covers twice of the current base fee). Legacy (and EIP-2930) transactions are replaced with the same type.
+// The access list of the original transaction is kept.
+func ReplacementFees(tx *ethtypes.Transaction, market FeeMarket) []interface{} {
+	if tx.Type() != ethtypes.DynamicFeeTxType {
+		price := maxBig(BumpFee(tx.GasPrice(), MinFeeBump), market.GasPrice)
+		if tx.Type() == ethtypes.AccessListTxType {
+			return []interface{}{
+				WithTxType{Type: ethtypes.AccessListTxType},
+				WithGasPrice{Price: price},
+				WithAccessList{List: tx.AccessList()},
+			}
+		}
+		return []interface{}{
+			WithTxType{Type: ethtypes.LegacyTxType},
+			WithGasPrice{Price: price},
+		}
+	}
+	tip := maxBig(BumpFee(tx.GasTipCap(), MinFeeBump), market.GasTipCap)
+	feeCap := maxBig(BumpFee(tx.GasFeeCap(), MinFeeBump), tip)
+	if market.BaseFee != nil {
+		feeCap = maxBig(feeCap, new(big.Int).Add(new(big.Int).Mul(market.BaseFee, big.NewInt(2)), tip))
+	}
+	opts := []interface{}{
+		WithTxType{Type: ethtypes.DynamicFeeTxType},
+		WithGasTipCap{Value: tip},
+		WithGasFeeCap{Value: feeCap},
+	}
+	if len(tx.AccessList()) > 0 {
+		opts = append(opts, WithAccessList{List: tx.AccessList()})
+	}
+	return opts
+}
+
+// maxBig returns the higher value (nil values are ignored).
+func maxBig(a *big.Int, b *big.Int) *big.Int {
+	if b == nil || (a != nil && a.Cmp(b) >= 0) {
+		return a
+	}
+	return b
+}
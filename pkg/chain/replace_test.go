@@ -0,0 +1,77 @@
+package chain
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+)
+
+func TestBumpFee(t *testing.T) {
+	require.Equal(t, big.NewInt(110), BumpFee(big.NewInt(100), 10))
+	require.Equal(t, big.NewInt(13), BumpFee(big.NewInt(11), 10))
+	require.Zero(t, BumpFee(big.NewInt(0), 10).Sign())
+}
+
+func TestReplacementFees(t *testing.T) {
+	gwei := func(v int64) *big.Int {
+		return new(big.Int).Mul(big.NewInt(v), big.NewInt(1_000_000_000))
+	}
+	dynamic := ethtypes.NewTx(&ethtypes.DynamicFeeTx{
+		GasTipCap: gwei(2),
+		GasFeeCap: gwei(50),
+	})
+
+	// quiet market: 10% bump on both
+	fees := ReplacementFees(dynamic, FeeMarket{BaseFee: gwei(10), GasTipCap: gwei(1), GasPrice: gwei(11)})
+	require.Equal(t, []interface{}{
+		WithTxType{Type: ethtypes.DynamicFeeTxType},
+		WithGasTipCap{Value: big.NewInt(2_200_000_000)},
+		WithGasFeeCap{Value: gwei(55)},
+	}, fees)
+
+	// base fee is increased: fee cap follows it
+	fees = ReplacementFees(dynamic, FeeMarket{BaseFee: gwei(40), GasTipCap: gwei(3), GasPrice: gwei(43)})
+	require.Equal(t, []interface{}{
+		WithTxType{Type: ethtypes.DynamicFeeTxType},
+		WithGasTipCap{Value: gwei(3)},
+		WithGasFeeCap{Value: gwei(83)},
+	}, fees)
+
+	legacy := ethtypes.NewTx(&ethtypes.LegacyTx{
+		GasPrice: gwei(20),
+	})
+	fees = ReplacementFees(legacy, FeeMarket{GasPrice: gwei(10)})
+	require.Equal(t, []interface{}{
+		WithTxType{Type: ethtypes.LegacyTxType},
+		WithGasPrice{Price: gwei(22)},
+	}, fees)
+	fees = ReplacementFees(legacy, FeeMarket{GasPrice: gwei(30)})
+	require.Equal(t, WithGasPrice{Price: gwei(30)}, fees[1])
+
+	list := ethtypes.AccessList{{Address: common.HexToAddress("0x01")}}
+	accessList := ethtypes.NewTx(&ethtypes.AccessListTx{
+		GasPrice:   gwei(20),
+		AccessList: list,
+	})
+	fees = ReplacementFees(accessList, FeeMarket{GasPrice: gwei(10)})
+	require.Equal(t, []interface{}{
+		WithTxType{Type: ethtypes.AccessListTxType},
+		WithGasPrice{Price: gwei(22)},
+		WithAccessList{List: list},
+	}, fees)
+
+	dynamicWithList := ethtypes.NewTx(&ethtypes.DynamicFeeTx{
+		GasTipCap:  gwei(2),
+		GasFeeCap:  gwei(50),
+		AccessList: list,
+	})
+	fees = ReplacementFees(dynamicWithList, FeeMarket{BaseFee: gwei(10), GasTipCap: gwei(1), GasPrice: gwei(11)})
+	require.Equal(t, []interface{}{
+		WithTxType{Type: ethtypes.DynamicFeeTxType},
+		WithGasTipCap{Value: big.NewInt(2_200_000_000)},
+		WithGasFeeCap{Value: gwei(55)},
+		WithAccessList{List: list},
+	}, fees)
+}
@@ -0,0 +1,189 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+	"math/big"
+	"strings"
+)
+
+var (
+	errorSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+)
+
+// panicReasons are the descriptions of the solidity panic codes.
+var panicReasons = map[uint64]string{
+	0x00: "generic compiler inserted panic",
+	0x01: "assertion failed",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "conversion into non-existent enum type",
+	0x22: "access to incorrectly encoded storage byte array",
+	0x31: "pop() on an empty array",
+	0x32: "array index out of bounds",
+	0x41: "too much memory allocated",
+	0x51: "call to a zero-initialized internal function",
+}
+
+// RevertError is an execution error with the revert data returned by the node.
+type RevertError struct {
+	Data   []byte
+	Reason string
+	err    error
+}
+
+// NewRevertError wraps the RPC error to a RevertError if revert data is available. Other errors are returned as is.
+func NewRevertError(err error) error {
+	data, found := RevertData(err)
+	if !found || len(data) == 0 {
+		return err
+	}
+	return newRevertError(data, err)
+}
+
+func newRevertError(data []byte, err error) *RevertError {
+	revertErr := &RevertError{
+		Data: data,
+		err:  err,
+	}
+	if reason, err := DecodeRevert(data); err == nil {
+		revertErr.Reason = reason
+	}
+	return revertErr
+}
+
+func (e *RevertError) Error() string {
+	if e.Reason != "" {
+		return "execution reverted: " + e.Reason
+	}
+	return fmt.Sprintf("%s (revert data: %s)", e.err.Error(), hexutil.Encode(e.Data))
+}
+
+func (e *RevertError) Unwrap() error {
+	return e.err
+}
+
+// DecodeCustomError decodes the revert data with the custom errors of the ABI. The error is modified in place, and true
+// is returned if the data is decoded.
+func (e *RevertError) DecodeCustomError(contractAbi abi.ABI) bool {
+	reason, err := DecodeCustomError(e.Data, contractAbi)
+	if err != nil {
+		return false
+	}
+	e.Reason = reason
+	return true
+}
+
+// DecodeCustomError decodes revert data as one of the custom errors (`error Name(...)`) of the ABI.
+func DecodeCustomError(data []byte, contractAbi abi.ABI) (string, error) {
+	if len(data) < 4 {
+		return "", errors.New("Revert data is shorter than the 4 bytes selector")
+	}
+	for _, e := range contractAbi.Errors {
+		if !bytes.Equal(e.ID[:4], data[:4]) {
+			continue
+		}
+		values, err := e.Inputs.Unpack(data[4:])
+		if err != nil {
+			return "", errors.Wrapf(err, "Couldn't unpack arguments of %s", e.Sig)
+		}
+		var args []string
+		for ix, v := range values {
+			args = append(args, fmt.Sprintf("%s=%v", e.Inputs[ix].Name, v))
+		}
+		return fmt.Sprintf("%s(%s)", e.Name, strings.Join(args, ", ")), nil
+	}
+	return "", errors.Errorf("No custom error is found for selector %x", data[:4])
+}
+
+// DecodeRevertWithAbi decodes the revert data of the error with the custom errors of the ABI (if the error has any).
+func DecodeRevertWithAbi(err error, contractAbi abi.ABI) error {
+	var revertErr *RevertError
+	if errors.As(err, &revertErr) {
+		revertErr.DecodeCustomError(contractAbi)
+	}
+	return err
+}
+
+// RevertData returns the raw revert data attached to an RPC error (if any).
+func RevertData(err error) ([]byte, bool) {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil, false
+	}
+	switch data := dataErr.ErrorData().(type) {
+	case string:
+		decoded, err := hexutil.Decode(data)
+		if err != nil {
+			return nil, false
+		}
+		return decoded, true
+	case []byte:
+		return data, true
+	}
+	return nil, false
+}
+
+// DecodeRevert decodes the standard Error(string) and Panic(uint256) revert data.
+func DecodeRevert(data []byte) (string, error) {
+	if len(data) < 4 {
+		return "", errors.New("Revert data is shorter than the 4 bytes selector")
+	}
+	switch {
+	case bytes.Equal(data[:4], errorSelector):
+		return abi.UnpackRevert(data)
+	case bytes.Equal(data[:4], panicSelector):
+		if len(data) != 36 {
+			return "", errors.New("Invalid Panic(uint256) revert data")
+		}
+		code := new(big.Int).SetBytes(data[4:])
+		reason, found := panicReasons[code.Uint64()]
+		if !found || !code.IsUint64() {
+			reason = "unknown panic code"
+		}
+		return fmt.Sprintf("panic 0x%x (%s)", code, reason), nil
+	}
+	return "", errors.Errorf("Unknown revert selector %x", data[:4])
+}
+
+// RevertReason replays a failed transaction on the state of the parent block and returns the reason of the revert.
+func RevertReason(ctx context.Context, client *ethclient.Client, tx *ethtypes.Transaction, receipt *ethtypes.Receipt) (string, error) {
+	from, err := ethtypes.Sender(ethtypes.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return "", errors.Wrap(err, "Couldn't recover sender of the transaction")
+	}
+	msg := ethereum.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+	var block *big.Int
+	if receipt.BlockNumber != nil && receipt.BlockNumber.Sign() > 0 {
+		block = new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+	}
+	_, err = client.CallContract(ctx, msg, block)
+	if err == nil {
+		return "", errors.New("Transaction is not reverted when replayed")
+	}
+	data, found := RevertData(err)
+	if !found || len(data) == 0 {
+		return err.Error(), nil
+	}
+	reason, decodeErr := DecodeRevert(data)
+	if decodeErr != nil {
+		return hexutil.Encode(data), nil
+	}
+	return reason, nil
+}
@@ -0,0 +1,76 @@
+package chain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestDecodeRevert(t *testing.T) {
+	// require(false, "Not enough Ether provided.")
+	data := hexutil.MustDecode("0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"000000000000000000000000000000000000000000000000000000000000001a" +
+		"4e6f7420656e6f7567682045746865722070726f76696465642e000000000000")
+	reason, err := DecodeRevert(data)
+	require.NoError(t, err)
+	require.Equal(t, "Not enough Ether provided.", reason)
+
+	data = append(hexutil.MustDecode("0x4e487b71"), common.LeftPadBytes([]byte{0x11}, 32)...)
+	reason, err = DecodeRevert(data)
+	require.NoError(t, err)
+	require.Equal(t, "panic 0x11 (arithmetic underflow or overflow)", reason)
+
+	_, err = DecodeRevert(hexutil.MustDecode("0x12345678"))
+	require.Error(t, err)
+}
+
+type revertStub struct {
+	data []byte
+}
+
+type revertStubError struct {
+	data []byte
+}
+
+func (e revertStubError) Error() string          { return "execution reverted" }
+func (e revertStubError) ErrorCode() int         { return 3 }
+func (e revertStubError) ErrorData() interface{} { return hexutil.Encode(e.data) }
+
+func (s *revertStub) Call(args map[string]interface{}, block string) (hexutil.Bytes, error) {
+	return nil, revertStubError{data: s.data}
+}
+
+func TestSendQueryCustomError(t *testing.T) {
+	contractAbi, err := abi.JSON(strings.NewReader(`[{"type":"error","name":"InsufficientBalance","inputs":[{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}]}]`))
+	require.NoError(t, err)
+
+	customErr := contractAbi.Errors["InsufficientBalance"]
+	data, err := customErr.Inputs.Pack(big.NewInt(1), big.NewInt(2))
+	require.NoError(t, err)
+	data = append(customErr.ID[:4], data...)
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	require.NoError(t, server.RegisterName("eth", &revertStub{data: data}))
+	client := ethclient.NewClient(rpc.DialInProc(server))
+	defer client.Close()
+
+	_, err = SendQuery(context.Background(), client, common.Address{}, common.Address{}, WithData{Data: []byte{1, 2, 3, 4}})
+	require.Error(t, err)
+
+	var revertErr *RevertError
+	require.True(t, errors.As(err, &revertErr))
+	require.Equal(t, data, revertErr.Data)
+
+	err = DecodeRevertWithAbi(err, contractAbi)
+	require.Equal(t, "CallContract is failed: execution reverted: InsufficientBalance(available=1, required=2)", err.Error())
+}
@@ -0,0 +1,108 @@
+package chain
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"math/big"
+)
+
+// UnsignedTx is the portable (JSON) representation of a transaction which can be signed without RPC access.
+type UnsignedTx struct {
+	Type                 hexutil.Uint64      `json:"type"`
+	ChainID              *hexutil.Big        `json:"chainId"`
+	From                 *common.Address     `json:"from,omitempty"`
+	Nonce                hexutil.Uint64      `json:"nonce"`
+	To                   *common.Address     `json:"to"`
+	Value                *hexutil.Big        `json:"value"`
+	Gas                  hexutil.Uint64      `json:"gas"`
+	GasPrice             *hexutil.Big        `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big        `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big        `json:"maxPriorityFeePerGas,omitempty"`
+	Data                 hexutil.Bytes       `json:"data"`
+	AccessList           ethtypes.AccessList `json:"accessList,omitempty"`
+}
+
+func NewUnsignedTx(tx *ethtypes.Transaction, chainID *big.Int, from common.Address) UnsignedTx {
+	value := new(big.Int)
+	if tx.Value() != nil {
+		value = tx.Value()
+	}
+	u := UnsignedTx{
+		Type:    hexutil.Uint64(tx.Type()),
+		ChainID: (*hexutil.Big)(chainID),
+		From:    &from,
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		To:      tx.To(),
+		Value:   (*hexutil.Big)(value),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Data:    tx.Data(),
+	}
+	if tx.Type() != ethtypes.LegacyTxType {
+		u.AccessList = tx.AccessList()
+	}
+	if tx.Type() == ethtypes.LegacyTxType || tx.Type() == ethtypes.AccessListTxType {
+		u.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	} else {
+		u.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		u.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	}
+	return u
+}
+
+// Transaction converts the portable representation back to a transaction.
+func (u UnsignedTx) Transaction() (*ethtypes.Transaction, error) {
+	if u.ChainID == nil {
+		return nil, errors.New("chainId is missing from the transaction")
+	}
+	value := new(big.Int)
+	if u.Value != nil {
+		value = u.Value.ToInt()
+	}
+	switch uint8(u.Type) {
+	case ethtypes.LegacyTxType:
+		if u.GasPrice == nil {
+			return nil, errors.New("gasPrice is missing from the legacy transaction")
+		}
+		return ethtypes.NewTx(&ethtypes.LegacyTx{
+			Nonce:    uint64(u.Nonce),
+			GasPrice: u.GasPrice.ToInt(),
+			Gas:      uint64(u.Gas),
+			To:       u.To,
+			Value:    value,
+			Data:     u.Data,
+		}), nil
+	case ethtypes.AccessListTxType:
+		if u.GasPrice == nil {
+			return nil, errors.New("gasPrice is missing from the EIP-2930 transaction")
+		}
+		return ethtypes.NewTx(&ethtypes.AccessListTx{
+			ChainID:    u.ChainID.ToInt(),
+			Nonce:      uint64(u.Nonce),
+			GasPrice:   u.GasPrice.ToInt(),
+			Gas:        uint64(u.Gas),
+			To:         u.To,
+			Value:      value,
+			Data:       u.Data,
+			AccessList: u.AccessList,
+		}), nil
+	case ethtypes.DynamicFeeTxType:
+		if u.MaxFeePerGas == nil || u.MaxPriorityFeePerGas == nil {
+			return nil, errors.New("maxFeePerGas and maxPriorityFeePerGas are required for dynamic fee transaction")
+		}
+		return ethtypes.NewTx(&ethtypes.DynamicFeeTx{
+			ChainID:    u.ChainID.ToInt(),
+			Nonce:      uint64(u.Nonce),
+			GasTipCap:  u.MaxPriorityFeePerGas.ToInt(),
+			GasFeeCap:  u.MaxFeePerGas.ToInt(),
+			Gas:        uint64(u.Gas),
+			To:         u.To,
+			Value:      value,
+			Data:       u.Data,
+			AccessList: u.AccessList,
+		}), nil
+	default:
+		return nil, errors.Errorf("Unsupported transaction type %d", u.Type)
+	}
+}
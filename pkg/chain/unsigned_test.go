@@ -0,0 +1,53 @@
+package chain
+
+import (
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+)
+
+func TestUnsignedTxRoundtrip(t *testing.T) {
+	to := common.HexToAddress("0x158d2c25ba6107b622f288663f50f53601ab6710")
+	from := common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826")
+	tx := ethtypes.NewTx(&ethtypes.DynamicFeeTx{
+		ChainID:   big.NewInt(5),
+		Nonce:     12,
+		GasTipCap: big.NewInt(1500000000),
+		GasFeeCap: big.NewInt(30000000000),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(1),
+		Data:      []byte{1, 2, 3},
+	})
+
+	content, err := json.Marshal(NewUnsignedTx(tx, big.NewInt(5), from))
+	require.NoError(t, err)
+
+	parsed := UnsignedTx{}
+	require.NoError(t, json.Unmarshal(content, &parsed))
+	require.Equal(t, from, *parsed.From)
+
+	restored, err := parsed.Transaction()
+	require.NoError(t, err)
+	require.Equal(t, tx.Hash(), restored.Hash())
+
+	tx = ethtypes.NewTx(&ethtypes.AccessListTx{
+		ChainID:    big.NewInt(5),
+		Nonce:      13,
+		GasPrice:   big.NewInt(30000000000),
+		Gas:        30000,
+		To:         &to,
+		Data:       []byte{1, 2, 3},
+		AccessList: ethtypes.AccessList{{Address: to, StorageKeys: []common.Hash{common.HexToHash("0x01")}}},
+	})
+	content, err = json.Marshal(NewUnsignedTx(tx, big.NewInt(5), from))
+	require.NoError(t, err)
+	parsed = UnsignedTx{}
+	require.NoError(t, json.Unmarshal(content, &parsed))
+	restored, err = parsed.Transaction()
+	require.NoError(t, err)
+	require.Equal(t, tx.Hash(), restored.Hash())
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -60,7 +61,7 @@ func SendQuery(ctx context.Context, client *ethclient.Client, sender common.Addr
 			Hex("from", sender.Bytes()).
 			Hex("resp", res).
 			Msg("CallContract")
-		return nil, errors.Wrap(err, "CallContract is failed")
+		return nil, errors.Wrap(NewRevertError(err), "CallContract is failed")
 	}
 
 	log.Debug().
@@ -82,10 +83,56 @@ func FunctionCallData(function string, argTypes abi.Arguments, args ...interface
 	return data, nil
 }
 
-func GetTransaction(ctx context.Context, client *ethclient.Client, hash common.Hash) (types.Item, error) {
-	tx, _, err := client.TransactionByHash(ctx, hash)
+// TransactionSource provides the transaction related data for GetTransaction.
+type TransactionSource interface {
+	// TransactionAndReceipt returns the transaction and the receipt (nil for pending transactions).
+	TransactionAndReceipt(ctx context.Context, hash common.Hash) (*ethtypes.Transaction, *ethtypes.Receipt, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*ethtypes.Header, error)
+}
+
+// clientSource is the TransactionSource which uses separated requests.
+type clientSource struct {
+	client *ethclient.Client
+}
+
+// NewTransactionSource returns the TransactionSource which uses the standard requests of the client.
+func NewTransactionSource(client *ethclient.Client) TransactionSource {
+	return clientSource{client: client}
+}
+
+func (s clientSource) TransactionAndReceipt(ctx context.Context, hash common.Hash) (*ethtypes.Transaction, *ethtypes.Receipt, error) {
+	tx, _, err := s.client.TransactionByHash(ctx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	receipt, err := s.client.TransactionReceipt(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return tx, nil, nil
+	}
 	if err != nil {
-		return types.Item{}, errors.Wrap(err, "Couldn't read transaction")
+		return nil, nil, err
+	}
+	return tx, receipt, nil
+}
+
+func (s clientSource) HeaderByHash(ctx context.Context, hash common.Hash) (*ethtypes.Header, error) {
+	return s.client.HeaderByHash(ctx, hash)
+}
+
+// TransactionDetails is the printable summary of the transaction, with the transaction and the receipt (nil for pending
+// transactions) it's created from.
+type TransactionDetails struct {
+	types.Item
+	Tx      *ethtypes.Transaction
+	Receipt *ethtypes.Receipt
+}
+
+// GetTransaction returns the details of the transaction. Client is used to replay failed transactions (to get the revert
+// reason).
+func GetTransaction(ctx context.Context, client *ethclient.Client, source TransactionSource, hash common.Hash) (TransactionDetails, error) {
+	tx, receipt, err := source.TransactionAndReceipt(ctx, hash)
+	if err != nil {
+		return TransactionDetails{}, errors.Wrap(err, "Couldn't read transaction")
 	}
 
 	i := types.Item{
@@ -134,9 +181,8 @@ func GetTransaction(ctx context.Context, client *ethclient.Client, hash common.H
 		},
 	}
 
-	receipt, err := client.TransactionReceipt(ctx, hash)
-	if err != nil {
-		return i, err
+	if receipt == nil {
+		return TransactionDetails{Item: i, Tx: tx}, ethereum.NotFound
 	} else {
 		i.Record.Fields = append(i.Record.Fields, []types.Field{
 			{
@@ -161,25 +207,36 @@ func GetTransaction(ctx context.Context, client *ethclient.Client, hash common.H
 			},
 		}...)
 
-		block, err := client.BlockByHash(ctx, receipt.BlockHash)
+		if receipt.Status == ethtypes.ReceiptStatusFailed {
+			reason, err := RevertReason(ctx, client, tx, receipt)
+			if err != nil {
+				log.Debug().Err(err).Msg("Couldn't get revert reason")
+			} else {
+				i.AddField("revert", reason)
+			}
+		}
+
+		header, err := source.HeaderByHash(ctx, receipt.BlockHash)
 		if err != nil {
-			return i, err
+			return TransactionDetails{Item: i, Tx: tx, Receipt: receipt}, err
+		}
+		if header.BaseFee != nil {
+			i.Record.Fields = append(i.Record.Fields,
+				types.Field{
+					Name:    "base-gas-fee",
+					Value:   header.BaseFee,
+					Printer: types.EthPrintType,
+				},
+			)
+			i.Record.Fields = append(i.Record.Fields,
+				types.Field{
+					Name:    "fee",
+					Value:   new(big.Int).Mul(header.BaseFee, big.NewInt(int64(receipt.GasUsed))),
+					Printer: types.EthPrintType,
+				},
+			)
 		}
-		i.Record.Fields = append(i.Record.Fields,
-			types.Field{
-				Name:    "base-gas-fee",
-				Value:   block.BaseFee(),
-				Printer: types.EthPrintType,
-			},
-		)
-		i.Record.Fields = append(i.Record.Fields,
-			types.Field{
-				Name:    "fee",
-				Value:   new(big.Int).Mul(block.BaseFee(), big.NewInt(int64(receipt.GasUsed))),
-				Printer: types.EthPrintType,
-			},
-		)
 	}
 
-	return i, nil
+	return TransactionDetails{Item: i, Tx: tx, Receipt: receipt}, nil
 }
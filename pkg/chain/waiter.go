@@ -0,0 +1,206 @@
+package chain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"math/big"
+	"time"
+)
+
+// TxSource is the part of the ethclient.Client which is required to track a sent transaction.
+type TxSource interface {
+	TransactionByHash(ctx context.Context, hash common.Hash) (*ethtypes.Transaction, bool, error)
+	TransactionReceipt(ctx context.Context, hash common.Hash) (*ethtypes.Receipt, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// TxStatus is the state of a sent transaction.
+type TxStatus string
+
+const (
+	// TxUnknown means that the transaction is not (yet) known by the node.
+	TxUnknown TxStatus = "unknown"
+	// TxPending means that the transaction is in the mempool.
+	TxPending TxStatus = "pending"
+	// TxMined means that the transaction is included, but doesn't have enough confirmations.
+	TxMined TxStatus = "mined"
+	// TxConfirmed means that the transaction is executed successfully, with the required confirmations.
+	TxConfirmed TxStatus = "confirmed"
+	// TxFailed means that the transaction is reverted (with the required confirmations).
+	TxFailed TxStatus = "failed"
+	// TxReplaced means that another transaction with the same nonce is included.
+	TxReplaced TxStatus = "replaced"
+	// TxDropped means that the transaction disappeared from the mempool without being included.
+	TxDropped TxStatus = "dropped"
+	// TxTimeout means that the transaction didn't reach a final state in time.
+	TxTimeout TxStatus = "timeout"
+)
+
+// Final returns true if the status won't change any more (or waiting is finished).
+func (s TxStatus) Final() bool {
+	switch s {
+	case TxConfirmed, TxFailed, TxReplaced, TxDropped, TxTimeout:
+		return true
+	}
+	return false
+}
+
+// TxResult is the (last known) state of the transaction.
+type TxResult struct {
+	Hash   common.Hash
+	Status TxStatus
+	// Receipt is available for mined transactions.
+	Receipt       *ethtypes.Receipt
+	Confirmations uint64
+}
+
+// TxWaiter polls the state of a transaction until it's confirmed (with the required confirmations), reverted,
+// replaced by an other transaction with the same nonce, dropped, or the timeout is reached.
+type TxWaiter struct {
+	client TxSource
+	// Confirmations is the number of required blocks (including the block of the transaction).
+	Confirmations uint64
+	// Timeout of the waiting (0: no timeout).
+	Timeout      time.Duration
+	PollInterval time.Duration
+	// DropTimeout is the time after a transaction is reported as dropped if it's unknown by the node.
+	DropTimeout time.Duration
+	// OnStatus is called when the status (or the number of confirmations) is changed.
+	OnStatus func(TxResult)
+}
+
+func NewTxWaiter(client TxSource) *TxWaiter {
+	return &TxWaiter{
+		client:        client,
+		Confirmations: 1,
+		Timeout:       5 * time.Minute,
+		PollInterval:  time.Second,
+		DropTimeout:   time.Minute,
+	}
+}
+
+// Wait polls the state of the transaction until it reaches a final state. Timeout is reported as TxTimeout status
+// (not as error).
+func (w *TxWaiter) Wait(ctx context.Context, hash common.Hash) (TxResult, error) {
+	if w.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.Timeout)
+		defer cancel()
+	}
+	t := txTracker{
+		waiter:   w,
+		result:   TxResult{Hash: hash, Status: TxUnknown},
+		lastSeen: time.Now(),
+	}
+	for {
+		changed, err := t.poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			return t.result, err
+		}
+		if changed && w.OnStatus != nil {
+			w.OnStatus(t.result)
+		}
+		if t.result.Status.Final() {
+			return t.result, nil
+		}
+		select {
+		case <-time.After(w.PollInterval):
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				t.result.Status = TxTimeout
+				return t.result, nil
+			}
+			return t.result, ctx.Err()
+		}
+	}
+}
+
+// txTracker is the state of one Wait.
+type txTracker struct {
+	waiter *TxWaiter
+	result TxResult
+	// sender and nonce are known after the transaction is seen by the node.
+	sender *common.Address
+	nonce  uint64
+	// lastSeen is the last time when the transaction was known by the node (or the start of the waiting).
+	lastSeen time.Time
+}
+
+// poll refreshes the state of the transaction, and returns true if it's changed.
+func (t *txTracker) poll(ctx context.Context) (bool, error) {
+	status, confirmations := t.result.Status, t.result.Confirmations
+	err := t.refresh(ctx)
+	return status != t.result.Status || confirmations != t.result.Confirmations, err
+}
+
+func (t *txTracker) refresh(ctx context.Context) error {
+	client := t.waiter.client
+	hash := t.result.Hash
+
+	receipt, err := client.TransactionReceipt(ctx, hash)
+	if err != nil && !errors.Is(err, ethereum.NotFound) {
+		return errors.Wrap(err, "Couldn't get transaction receipt")
+	}
+	if receipt != nil {
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			return err
+		}
+		t.result.Receipt = receipt
+		t.result.Confirmations = 0
+		if head >= receipt.BlockNumber.Uint64() {
+			t.result.Confirmations = head - receipt.BlockNumber.Uint64() + 1
+		}
+		t.result.Status = TxMined
+		if t.result.Confirmations >= t.waiter.Confirmations {
+			t.result.Status = TxConfirmed
+			if receipt.Status == ethtypes.ReceiptStatusFailed {
+				t.result.Status = TxFailed
+			}
+		}
+		return nil
+	}
+	// receipt can disappear due to reorg
+	t.result.Receipt = nil
+	t.result.Confirmations = 0
+
+	tx, _, err := client.TransactionByHash(ctx, hash)
+	if err != nil && !errors.Is(err, ethereum.NotFound) {
+		return errors.Wrap(err, "Couldn't get transaction")
+	}
+	if tx != nil {
+		t.lastSeen = time.Now()
+		t.result.Status = TxPending
+		if t.sender == nil {
+			sender, err := ethtypes.Sender(ethtypes.LatestSignerForChainID(tx.ChainId()), tx)
+			if err == nil {
+				t.sender = &sender
+				t.nonce = tx.Nonce()
+			}
+		}
+	} else if time.Since(t.lastSeen) > t.waiter.DropTimeout {
+		t.result.Status = TxDropped
+	}
+
+	if t.sender != nil {
+		mined, err := client.NonceAt(ctx, *t.sender, nil)
+		if err != nil {
+			return errors.Wrap(err, "Couldn't get nonce")
+		}
+		if mined > t.nonce {
+			// the transaction itself can be mined since the receipt check
+			receipt, err = client.TransactionReceipt(ctx, hash)
+			if err == nil && receipt != nil {
+				return nil
+			}
+			log.Debug().Uint64("nonce", t.nonce).Uint64("mined", mined).Msg("Nonce is used by an other transaction")
+			t.result.Status = TxReplaced
+		}
+	}
+	return nil
+}
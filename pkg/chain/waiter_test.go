@@ -0,0 +1,160 @@
+package chain
+
+import (
+	"context"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTxChain is a scripted node: the transaction is pending, then it's mined (or replaced) at the given poll.
+type fakeTxChain struct {
+	mu      sync.Mutex
+	tx      *ethtypes.Transaction
+	receipt *ethtypes.Receipt
+	head    uint64
+	nonce   uint64
+	// step is called before each poll (receipt request) to change the state.
+	step  func(c *fakeTxChain, poll int)
+	polls int
+}
+
+func (f *fakeTxChain) TransactionByHash(ctx context.Context, hash common.Hash) (*ethtypes.Transaction, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.tx == nil || f.tx.Hash() != hash {
+		return nil, false, ethereum.NotFound
+	}
+	return f.tx, f.receipt == nil, nil
+}
+
+func (f *fakeTxChain) TransactionReceipt(ctx context.Context, hash common.Hash) (*ethtypes.Receipt, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.polls++
+	if f.step != nil {
+		f.step(f, f.polls)
+	}
+	if f.receipt == nil || f.receipt.TxHash != hash {
+		return nil, ethereum.NotFound
+	}
+	return f.receipt, nil
+}
+
+func (f *fakeTxChain) BlockNumber(ctx context.Context) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.head, nil
+}
+
+func (f *fakeTxChain) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.nonce, nil
+}
+
+func signedTestTx(t *testing.T, nonce uint64) *ethtypes.Transaction {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	chainID := big.NewInt(5)
+	tx, err := ethtypes.SignNewTx(key, ethtypes.LatestSignerForChainID(chainID), &ethtypes.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(10),
+		Gas:       21000,
+	})
+	require.NoError(t, err)
+	return tx
+}
+
+func testWaiter(f *fakeTxChain) *TxWaiter {
+	w := NewTxWaiter(f)
+	w.PollInterval = time.Millisecond
+	w.DropTimeout = 50 * time.Millisecond
+	w.Timeout = time.Second
+	return w
+}
+
+func TestTxWaiterConfirmations(t *testing.T) {
+	tx := signedTestTx(t, 3)
+	f := &fakeTxChain{tx: tx, head: 9, nonce: 3}
+	f.step = func(c *fakeTxChain, poll int) {
+		if poll == 3 {
+			c.receipt = &ethtypes.Receipt{TxHash: tx.Hash(), BlockNumber: big.NewInt(10), Status: ethtypes.ReceiptStatusSuccessful}
+			c.nonce = 4
+		}
+		if poll > 3 {
+			c.head = 9 + uint64(poll-3)
+		}
+	}
+	w := testWaiter(f)
+	w.Confirmations = 3
+	var statuses []TxStatus
+	w.OnStatus = func(r TxResult) {
+		statuses = append(statuses, r.Status)
+	}
+	res, err := w.Wait(context.Background(), tx.Hash())
+	require.NoError(t, err)
+	require.Equal(t, TxConfirmed, res.Status)
+	require.Equal(t, uint64(3), res.Confirmations)
+	require.Equal(t, []TxStatus{TxPending, TxMined, TxMined, TxMined, TxConfirmed}, statuses)
+
+	f.receipt.Status = ethtypes.ReceiptStatusFailed
+	res, err = w.Wait(context.Background(), tx.Hash())
+	require.NoError(t, err)
+	require.Equal(t, TxFailed, res.Status)
+}
+
+func TestTxWaiterReplaced(t *testing.T) {
+	tx := signedTestTx(t, 3)
+	f := &fakeTxChain{tx: tx, head: 9, nonce: 3}
+	f.step = func(c *fakeTxChain, poll int) {
+		if poll == 3 {
+			// other transaction with the same nonce is mined
+			c.tx = nil
+			c.nonce = 4
+		}
+	}
+	res, err := testWaiter(f).Wait(context.Background(), tx.Hash())
+	require.NoError(t, err)
+	require.Equal(t, TxReplaced, res.Status)
+}
+
+func TestTxWaiterDropped(t *testing.T) {
+	tx := signedTestTx(t, 3)
+	f := &fakeTxChain{head: 9, nonce: 3}
+	res, err := testWaiter(f).Wait(context.Background(), tx.Hash())
+	require.NoError(t, err)
+	require.Equal(t, TxDropped, res.Status)
+}
+
+func TestTxWaiterTimeout(t *testing.T) {
+	tx := signedTestTx(t, 3)
+	f := &fakeTxChain{tx: tx, head: 9, nonce: 3}
+	w := testWaiter(f)
+	w.Timeout = 50 * time.Millisecond
+	res, err := w.Wait(context.Background(), tx.Hash())
+	require.NoError(t, err)
+	require.Equal(t, TxTimeout, res.Status)
+}
+
+func TestEthWaiterSettings(t *testing.T) {
+	eth, err := NewEth(types.ChainConfig{RPCURL: "http://localhost:1", ChainID: 5}, false, 0, FeeSettings{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), eth.Waiter().Confirmations)
+	require.Equal(t, 5*time.Minute, eth.Waiter().Timeout)
+
+	// used by Call, too
+	eth.WaitConfirmations = 3
+	eth.WaitTimeout = 0
+	require.Equal(t, uint64(3), eth.Waiter().Confirmations)
+	require.Zero(t, eth.Waiter().Timeout)
+}
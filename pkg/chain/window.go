@@ -0,0 +1,102 @@
+package chain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"math/big"
+	"strings"
+)
+
+// LogFilterer is the part of the ethclient.Client which is required to download logs.
+type LogFilterer interface {
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethtypes.Log, error)
+}
+
+// LogWindow downloads logs of long block ranges with adaptive window size: the window is halved when the provider
+// refuses the request (too many results, too large range), and grows again after successful requests.
+type LogWindow struct {
+	// Size is the current number of blocks per request.
+	Size uint64
+	Min  uint64
+	Max  uint64
+}
+
+func NewLogWindow(size uint64) *LogWindow {
+	if size == 0 {
+		size = 2000
+	}
+	return &LogWindow{
+		Size: size,
+		Min:  1,
+		Max:  size * 16,
+	}
+}
+
+// limitErrors are the (lowercase) error messages of the providers when the result (or the range) is too large.
+var limitErrors = []string{
+	"too many",
+	"more than",
+	"limit exceeded",
+	"block range",
+	"range is too large",
+	"range too large",
+	"response size",
+	"exceed",
+	"timeout",
+	"timed out",
+}
+
+// IsLogLimitError returns true if the eth_getLogs request can be retried with smaller range.
+func IsLogLimitError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, e := range limitErrors {
+		if strings.Contains(msg, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetch downloads the logs of the block range (inclusive). Handler is called for each downloaded window in order.
+func (w *LogWindow) Fetch(ctx context.Context, client LogFilterer, q ethereum.FilterQuery, from uint64, to uint64, handler func(logs []ethtypes.Log, from uint64, to uint64) error) error {
+	q.BlockHash = nil
+	for start := from; start <= to; {
+		end := start + w.Size - 1
+		if end > to || end < start {
+			end = to
+		}
+		q.FromBlock = new(big.Int).SetUint64(start)
+		q.ToBlock = new(big.Int).SetUint64(end)
+		logs, err := client.FilterLogs(ctx, q)
+		if err != nil {
+			if ctx.Err() == nil && IsLogLimitError(err) && end > start && w.Size > w.Min {
+				w.Size = (end - start + 1) / 2
+				if w.Size < w.Min {
+					w.Size = w.Min
+				}
+				log.Debug().Err(err).Uint64("window", w.Size).Msg("Reducing log window")
+				continue
+			}
+			return errors.Wrapf(err, "Couldn't get logs between block %d and %d", start, end)
+		}
+		log.Debug().Uint64("from", start).Uint64("to", end).Int("logs", len(logs)).Msg("Logs downloaded")
+		err = handler(logs, start, end)
+		if err != nil {
+			return err
+		}
+		if end == to {
+			break
+		}
+		start = end + 1
+		if w.Size < w.Max {
+			w.Size *= 2
+			if w.Size > w.Max {
+				w.Size = w.Max
+			}
+		}
+	}
+	return nil
+}
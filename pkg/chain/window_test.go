@@ -0,0 +1,57 @@
+package chain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// limitedFilterer returns one log per block, and refuses the requests with more than limit results.
+type limitedFilterer struct {
+	limit    int
+	requests int
+}
+
+func (f *limitedFilterer) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]ethtypes.Log, error) {
+	f.requests++
+	from, to := q.FromBlock.Uint64(), q.ToBlock.Uint64()
+	if int(to-from+1) > f.limit {
+		return nil, errors.Errorf("query returned more than %d results", f.limit)
+	}
+	var logs []ethtypes.Log
+	for i := from; i <= to; i++ {
+		logs = append(logs, ethtypes.Log{BlockNumber: i})
+	}
+	return logs, nil
+}
+
+func TestLogWindow(t *testing.T) {
+	client := &limitedFilterer{limit: 10}
+	window := NewLogWindow(100)
+	var blocks []uint64
+	next := uint64(5)
+	err := window.Fetch(context.Background(), client, ethereum.FilterQuery{}, 5, 304, func(logs []ethtypes.Log, from uint64, to uint64) error {
+		require.Equal(t, next, from)
+		next = to + 1
+		for _, l := range logs {
+			blocks = append(blocks, l.BlockNumber)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(305), next)
+	require.Len(t, blocks, 300)
+	for i, b := range blocks {
+		require.Equal(t, uint64(i+5), b)
+	}
+	require.LessOrEqual(t, window.Size, uint64(20))
+
+	// single block is refused: no smaller window to retry with
+	err = (&LogWindow{Size: 1, Min: 1, Max: 1}).Fetch(context.Background(), &limitedFilterer{limit: 0}, ethereum.FilterQuery{}, 0, 10, func(logs []ethtypes.Log, from uint64, to uint64) error {
+		return nil
+	})
+	require.Error(t, err)
+}
@@ -8,33 +8,67 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"github.com/zeebo/errs/v2"
 	"github.com/zksync-sdk/zksync2-go"
 	"math/big"
+	"sync"
 )
 
 type Zksync2 struct {
-	zk *zksync2.DefaultProvider
+	cfg    types.ChainConfig
+	client *ethclient.Client
+
+	once  sync.Once
+	zk    *zksync2.DefaultProvider
+	zkErr error
 }
 
 var _ ChainClient = &Zksync2{}
 
-func NewZksync2(url string, confirm bool) (*Zksync2, error) {
-	zkSyncProvider, err := zksync2.NewDefaultProvider(url)
+// NewZksync2 creates the zkSync client. Standard eth_ requests use all the endpoints of the chain (see DialChain), zks_
+// specific requests are sent to the best endpoint, selected at the first zks_ request.
+func NewZksync2(cfg types.ChainConfig, confirm bool) (*Zksync2, error) {
+	if len(cfg.Endpoints()) == 0 {
+		return nil, errors.Errorf("No RPC URL is configured for chain %s", cfg.Name)
+	}
+	rpcClient, err := DialChain(context.Background(), cfg)
 	if err != nil {
-		return nil, errs.Wrap(err)
+		return nil, err
 	}
-
 	return &Zksync2{
-		zk: zkSyncProvider,
+		cfg:    cfg,
+		client: ethclient.NewClient(rpcClient),
 	}, nil
 }
 
+// provider returns the zkSync provider (created at the first call, when the endpoints are checked).
+func (z *Zksync2) provider() (*zksync2.DefaultProvider, error) {
+	z.once.Do(func() {
+		urls := z.cfg.Endpoints()
+		url := urls[0]
+		if len(urls) > 1 {
+			url = urls[rankEndpoints(CheckEndpoints(context.Background(), urls, z.cfg.ChainID))[0]]
+		}
+		z.zk, z.zkErr = zksync2.NewDefaultProvider(url)
+		if z.zkErr != nil {
+			z.zkErr = errs.Wrap(z.zkErr)
+			return
+		}
+		z.zk.Client = z.client
+	})
+	return z.zk, z.zkErr
+}
+
 func (z *Zksync2) Balance(ctx context.Context, account common.Address) (decimal.Decimal, error) {
-	val, err := z.zk.GetBalance(account, zksync2.BlockNumberCommitted)
+	zk, err := z.provider()
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	val, err := zk.GetBalance(account, zksync2.BlockNumberCommitted)
 	if err != nil {
 		return decimal.Decimal{}, nil
 	}
@@ -42,7 +76,7 @@ func (z *Zksync2) Balance(ctx context.Context, account common.Address) (decimal.
 }
 
 func (z *Zksync2) TokenBalance(ctx context.Context, token common.Address, account common.Address) (*big.Int, error) {
-	res, err := Query(ctx, z.zk.Client, types.WithoutAddressResolution{}, account, token, "balanceOf(address)uint256", account.String())
+	res, err := Query(ctx, z.client, types.WithoutAddressResolution{}, account, token, "balanceOf(address)uint256", account.String())
 	if err != nil {
 		return big.NewInt(0), err
 	}
@@ -52,11 +86,11 @@ func (z *Zksync2) TokenBalance(ctx context.Context, token common.Address, accoun
 
 func (z *Zksync2) TokenInfo(ctx context.Context, token common.Address) (TokenInfo, error) {
 	t := TokenInfo{}
-	symbol, err := Query(ctx, z.zk.Client, types.WithoutAddressResolution{}, token, token, "symbol()string")
+	symbol, err := Query(ctx, z.client, types.WithoutAddressResolution{}, token, token, "symbol()string")
 	if err == nil {
 		t.Symbol = symbol[0].(string)
 	}
-	dec, err := Query(ctx, z.zk.Client, types.WithoutAddressResolution{}, token, token, "decimals()uint8")
+	dec, err := Query(ctx, z.client, types.WithoutAddressResolution{}, token, token, "decimals()uint8")
 	if err == nil {
 		t.Decimal = dec[0].(uint8)
 	}
@@ -64,12 +98,12 @@ func (z *Zksync2) TokenInfo(ctx context.Context, token common.Address) (TokenInf
 	return t, nil
 }
 
-func (z *Zksync2) GetTransaction(ctx context.Context, hash common.Hash) (types.Item, error) {
-	return GetTransaction(ctx, z.zk.Client, hash)
+func (z *Zksync2) GetTransaction(ctx context.Context, hash common.Hash) (TransactionDetails, error) {
+	return GetTransaction(ctx, z.client, clientSource{client: z.client}, hash)
 }
 
 func (z *Zksync2) GetChainID(ctx context.Context) (int64, error) {
-	v, err := z.zk.ChainID(ctx)
+	v, err := z.client.ChainID(ctx)
 	return v.Int64(), err
 }
 
@@ -78,21 +112,25 @@ func (z *Zksync2) GetChainInfo(ctx context.Context) (types.Item, error) {
 		Fields: []types.Field{},
 	}
 
-	chainID, err := z.zk.ChainID(ctx)
+	chainID, err := z.client.ChainID(ctx)
 	if err != nil {
 		r.AddField("chainID", "??? "+err.Error())
 	} else {
 		r.AddField("chainID", chainID)
 	}
 
-	networkID, err := z.zk.NetworkID(ctx)
+	networkID, err := z.client.NetworkID(ctx)
 	if err != nil {
 		r.AddField("networkID", "??? "+err.Error())
 	} else {
 		r.AddField("networkID", networkID)
 	}
 
-	bc, err := z.zk.ZksGetBridgeContracts()
+	zk, err := z.provider()
+	if err != nil {
+		return types.Item{}, err
+	}
+	bc, err := zk.ZksGetBridgeContracts()
 	if err != nil {
 		r.AddField("bridge", "??? "+err.Error())
 	} else {
@@ -112,7 +150,7 @@ func (z *Zksync2) GetAccountInfo(ctx context.Context, account common.Address) (t
 	panic("implement me")
 }
 
-func (z *Zksync2) SendTransaction(ctx context.Context, from types.Account, to *common.Address, options ...interface{}) (common.Hash, error) {
+func (z *Zksync2) SendTransaction(ctx context.Context, from types.Signer, to *common.Address, options ...interface{}) (common.Hash, error) {
 	tx := zksync2.CreateFunctionCallTransaction(
 		from.Address(),
 		*to,
@@ -127,22 +165,26 @@ func (z *Zksync2) SendTransaction(ctx context.Context, from types.Account, to *c
 		return common.Hash{}, errs.Wrap(err)
 	}
 
-	nonce, err := z.zk.NonceAt(ctx, from.Address(), nil)
+	nonce, err := z.client.NonceAt(ctx, from.Address(), nil)
 	if err != nil {
 		return common.Hash{}, errs.Wrap(err)
 	}
 
-	gas, err := z.zk.EstimateGas(tx)
+	zk, err := z.provider()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	gas, err := zk.EstimateGas(tx)
 	if err != nil {
-		return common.Hash{}, fmt.Errorf("failed to EstimateGas: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to EstimateGas: %w", NewRevertError(err))
 	}
 
-	gasPrice, err := z.zk.GetGasPrice()
+	gasPrice, err := zk.GetGasPrice()
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("failed to GetGasPrice: %w", err)
 	}
 
-	chainId, err := z.zk.ChainID(ctx)
+	chainId, err := z.client.ChainID(ctx)
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("failed to GetChainID: %w", err)
 	}
@@ -161,17 +203,7 @@ func (z *Zksync2) SendTransaction(ctx context.Context, from types.Account, to *c
 			ErgsPerPubdata: zksync2.NewBig(160000),
 		})
 
-	ethereumSigner, err := zksync2.NewEthSignerFromRawPrivateKey(from.PrivateKey().D.Bytes(), 280)
-	if err != nil {
-		return common.Hash{}, err
-	}
-
-	domain := ethereumSigner.GetDomain()
-	signature, err := ethereumSigner.SignTypedData(domain, data)
-	if err != nil {
-		return common.Hash{}, err
-	}
-
+	domain := zksync2.DefaultEip712Domain(280)
 	typedData := apitypes.TypedData{
 		Types: apitypes.Types{
 			data.GetEIP712Type():   data.GetEIP712Types(),
@@ -181,7 +213,11 @@ func (z *Zksync2) SendTransaction(ctx context.Context, from types.Account, to *c
 		Domain:      domain.GetEIP712Domain(),
 		Message:     data.GetEIP712Message(),
 	}
-	hash, err := ethereumSigner.HashTypedData(typedData)
+	signature, err := from.SignTypedData(typedData)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
 	if err != nil {
 		return common.Hash{}, err
 	}
@@ -189,7 +225,7 @@ func (z *Zksync2) SendTransaction(ctx context.Context, from types.Account, to *c
 	h := common.Hash{}
 	copy(h[:], hash)
 	rawTx, err := data.RLPValues(signature)
-	_, err = z.zk.SendRawTransaction(rawTx)
+	_, err = zk.SendRawTransaction(rawTx)
 	if err != nil {
 		return h, err
 	}
@@ -199,8 +235,12 @@ func (z *Zksync2) SendTransaction(ctx context.Context, from types.Account, to *c
 
 }
 
+func (z *Zksync2) Waiter() *TxWaiter {
+	return NewTxWaiter(z.client)
+}
+
 func (z *Zksync2) SendQuery(ctx context.Context, from common.Address, to common.Address, options ...interface{}) ([]byte, error) {
-	return SendQuery(ctx, z.zk.Client, from, to, options)
+	return SendQuery(ctx, z.client, from, to, options)
 }
 
 func optionForZksyncTx(tx *zksync2.Transaction, opts ...interface{}) error {
@@ -212,7 +252,7 @@ func optionForZksyncTx(tx *zksync2.Transaction, opts ...interface{}) error {
 			val := hexutil.Big(*o.Value)
 			tx.Value = &val
 		default:
-			return errors.Errorf("Unsupported option type %t:", opt)
+			return errors.Errorf("Unsupported option type %T", opt)
 		}
 	}
 	return nil
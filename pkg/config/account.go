@@ -1,9 +1,11 @@
 package config
 
 import (
+	"crypto/ecdsa"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"github.com/elek/cethacea/pkg/signer"
 	"github.com/elek/cethacea/pkg/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/pkg/errors"
@@ -28,6 +30,15 @@ func NewAccountRepo(selected string) (*AccountRepo, error) {
 		return nil, err
 	}
 
+	for ix, a := range accounts {
+		if a.Mnemonic != "" {
+			accounts[ix], err = DeriveAccount(a, -1)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	if len(accounts) == 0 && (selected == "" || selected == "<generated>") {
 		key := make([]byte, 32)
 		_, _ = rand.Read(key)
@@ -47,15 +58,24 @@ func NewAccountRepo(selected string) (*AccountRepo, error) {
 				return nil, err
 			}
 			keyString := strings.TrimSpace(string(content))
-			_, err = crypto.HexToECDSA(keyString)
-			if err != nil {
-				return nil, err
-			}
+			if strings.HasPrefix(keyString, "{") {
+				// it's an encrypted keystore file
+				a, err := KeystoreAccount(selected, content)
+				if err != nil {
+					return nil, err
+				}
+				accounts = append(accounts, a)
+			} else {
+				_, err = crypto.HexToECDSA(keyString)
+				if err != nil {
+					return nil, err
+				}
 
-			accounts = append(accounts, types.Account{
-				Name:    selected,
-				Private: keyString,
-			})
+				accounts = append(accounts, types.Account{
+					Name:    selected,
+					Private: keyString,
+				})
+			}
 		}
 
 		_, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(selected), "0x"))
@@ -118,9 +138,49 @@ func (r *AccountRepo) GetCurrentAccount() (types.Account, error) {
 			return c, nil
 		}
 	}
+	if derived, found, err := r.derivedAccount(r.Selected); found || err != nil {
+		return derived, err
+	}
 	return types.Account{}, errors.New(fmt.Sprintf("Selected account %s is not found", r.Selected))
 }
 
+// GetCurrentSigner returns the signer of the selected account. Encrypted keys are decrypted only at the first signature.
+func (r *AccountRepo) GetCurrentSigner() (types.Signer, error) {
+	account, err := r.GetCurrentAccount()
+	if err != nil {
+		return nil, err
+	}
+	return NewSigner(account)
+}
+
+// NewSigner creates the signer implementation based on the type of the account.
+func NewSigner(a types.Account) (types.Signer, error) {
+	switch {
+	case a.Signer != "":
+		return signer.NewExternalSigner(a.Signer, a.Address())
+	case a.EncryptedMnemonic != "" && a.Private == "":
+		return signer.NewLazySigner(a.Address(), func() (*ecdsa.PrivateKey, error) {
+			pwd, err := ReadPassword("Passphrase of "+a.Name, false)
+			if err != nil {
+				return nil, err
+			}
+			unlocked, err := UnlockAccount(a, pwd)
+			if err != nil {
+				return nil, err
+			}
+			return unlocked.PrivateKey(), nil
+		}), nil
+	case a.Encrypted():
+		return signer.NewKeystoreSigner(a.Address(), []byte(a.Keystore), func() (string, error) {
+			return ReadPassword("Passphrase of "+a.Name, false)
+		}), nil
+	case a.PrivateKey() != nil:
+		return signer.NewKeySigner(a.PrivateKey()), nil
+	default:
+		return nil, errors.Errorf("Private key of account %s is not available", a.Name)
+	}
+}
+
 func (r *AccountRepo) ListAccounts() ([]types.Account, error) {
 	return r.Accounts, nil
 }
@@ -161,5 +221,37 @@ func (r *AccountRepo) GetAccount(name string) (types.Account, error) {
 			return a, nil
 		}
 	}
+	if derived, found, err := r.derivedAccount(name); found || err != nil {
+		return derived, err
+	}
 	return types.Account{}, errors.New("Default account is couldn't be identified")
 }
+
+// derivedAccount resolves names like `seed/3` to the 4th address of the HD wallet account `seed`. Encrypted mnemonic is
+// decrypted (with asking for the passphrase), as the derived address can't be calculated without it.
+func (r *AccountRepo) derivedAccount(name string) (types.Account, bool, error) {
+	base, index, ok := splitDerivedName(name)
+	if !ok {
+		return types.Account{}, false, nil
+	}
+	for _, a := range r.Accounts {
+		if a.Name == base && (a.Mnemonic != "" || a.EncryptedMnemonic != "") {
+			if a.Mnemonic == "" {
+				pwd, err := ReadPassword("Passphrase of "+a.Name, false)
+				if err != nil {
+					return types.Account{}, false, err
+				}
+				a, err = UnlockMnemonic(a, pwd)
+				if err != nil {
+					return types.Account{}, false, err
+				}
+			}
+			derived, err := DeriveAccount(a, index)
+			if err != nil {
+				return types.Account{}, false, err
+			}
+			return derived, true, nil
+		}
+	}
+	return types.Account{}, false, nil
+}
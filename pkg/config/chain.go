@@ -94,6 +94,33 @@ func (c *ChainRepo) AddChain(config types.ChainConfig) error {
 	return SaveYamlConfig(c.ConfigFile, &chains)
 }
 
+// CacheDir returns the directory of the local chain data cache ($XDG_CACHE_HOME/cethacea).
+func CacheDir() (string, error) {
+	cacheHome := os.Getenv("XDG_CACHE_HOME")
+	if cacheHome == "" {
+		usr, err := user.Current()
+		if err != nil {
+			return "", err
+		}
+		cacheHome = path.Join(usr.HomeDir, ".cache")
+	}
+	return path.Join(cacheHome, "cethacea"), nil
+}
+
+// StateDir returns the directory of the local state which shouldn't be deleted with the cache, like the reserved
+// nonces ($XDG_STATE_HOME/cethacea).
+func StateDir() (string, error) {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		usr, err := user.Current()
+		if err != nil {
+			return "", err
+		}
+		stateHome = path.Join(usr.HomeDir, ".local", "state")
+	}
+	return path.Join(stateHome, "cethacea"), nil
+}
+
 func globalChainConfig() (string, error) {
 	configHome := os.Getenv("XDG_CONFIG_HOME")
 	if configHome == "" {
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/pkg/errors"
 	"strings"
 )
@@ -109,3 +110,17 @@ func (c ContractRepo) GetContract(name string) (types.Contract, error) {
 func (c ContractRepo) GetCurrentContract() (types.Contract, error) {
 	return c.GetContract(c.Selected)
 }
+
+// GetContractByAddress returns the registered contract with the given address. Contracts registered for
+// a different chain are ignored.
+func (c ContractRepo) GetContractByAddress(address common.Address, chainID int64) (types.Contract, bool) {
+	for _, contract := range c.Contracts {
+		if contract.ChainID != 0 && chainID != 0 && contract.ChainID != chainID {
+			continue
+		}
+		if common.HexToAddress(contract.Address) == address {
+			return *contract, true
+		}
+	}
+	return types.Contract{}, false
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+	"github.com/tyler-smith/go-bip39"
+	"strconv"
+	"strings"
+)
+
+// DefaultDerivationPath is the derivation path of the first account of a new mnemonic.
+const DefaultDerivationPath = "m/44'/60'/0'/0/0"
+
+// NewMnemonic generates a new random BIP-39 mnemonic with the given number of words (12 or 24).
+func NewMnemonic(words int) (string, error) {
+	entropy, err := bip39.NewEntropy(words / 3 * 32)
+	if err != nil {
+		return "", errors.Wrap(err, "Couldn't generate entropy for mnemonic")
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// MnemonicAccount creates a new HD wallet account, where the key is derived from the mnemonic.
+func MnemonicAccount(name string, mnemonic string, path string) (types.Account, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return types.Account{}, errors.New("Mnemonic is invalid")
+	}
+	if path == "" {
+		path = DefaultDerivationPath
+	}
+	_, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		return types.Account{}, err
+	}
+	return types.Account{
+		Name:     name,
+		Mnemonic: mnemonic,
+		Path:     path,
+	}, nil
+}
+
+// EncryptMnemonic replaces the mnemonic of the HD wallet account with the encrypted form. The address of the derivation
+// path is saved, to make it usable without the passphrase.
+func EncryptMnemonic(a types.Account, passphrase string, scryptN int, scryptP int) (types.Account, error) {
+	derived, err := DeriveAccount(a, -1)
+	if err != nil {
+		return a, err
+	}
+	encrypted, err := keystore.EncryptDataV3([]byte(a.Mnemonic), []byte(passphrase), scryptN, scryptP)
+	if err != nil {
+		return a, errors.Wrap(err, "Couldn't encrypt mnemonic")
+	}
+	content, err := json.Marshal(encrypted)
+	if err != nil {
+		return a, err
+	}
+	return types.Account{
+		Name:              a.Name,
+		Public:            derived.Address().Hex(),
+		EncryptedMnemonic: string(content),
+		Path:              a.Path,
+	}, nil
+}
+
+// UnlockMnemonic decrypts the mnemonic of an encrypted HD wallet account.
+func UnlockMnemonic(a types.Account, passphrase string) (types.Account, error) {
+	if a.EncryptedMnemonic == "" || a.Mnemonic != "" {
+		return a, nil
+	}
+	encrypted := keystore.CryptoJSON{}
+	err := json.Unmarshal([]byte(a.EncryptedMnemonic), &encrypted)
+	if err != nil {
+		return a, errors.Wrapf(err, "Encrypted mnemonic of account %s is invalid", a.Name)
+	}
+	mnemonic, err := keystore.DecryptDataV3(encrypted, passphrase)
+	if err != nil {
+		return a, errors.Wrapf(err, "Couldn't decrypt the mnemonic of account %s", a.Name)
+	}
+	a.Mnemonic = string(mnemonic)
+	return a, nil
+}
+
+// DeriveAccount calculates the private key of an HD wallet account. Index >= 0 replaces the last component of the
+// derivation path.
+func DeriveAccount(a types.Account, index int) (types.Account, error) {
+	if a.EncryptedMnemonic != "" && a.Mnemonic == "" {
+		return a, errors.Errorf("Mnemonic of account %s is encrypted", a.Name)
+	}
+	if a.Mnemonic == "" {
+		return a, errors.Errorf("Account %s is not a mnemonic based account", a.Name)
+	}
+	path, err := accounts.ParseDerivationPath(a.Path)
+	if err != nil {
+		return a, err
+	}
+	if index >= 0 {
+		path[len(path)-1] = uint32(index)
+		a.Name = fmt.Sprintf("%s/%d", a.Name, index)
+		a.Path = path.String()
+	}
+
+	seed, err := bip39.NewSeedWithErrorChecking(a.Mnemonic, "")
+	if err != nil {
+		return a, err
+	}
+	key, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		return a, err
+	}
+	for _, n := range path {
+		key, err = key.Derive(n)
+		if err != nil {
+			return a, errors.Wrapf(err, "Couldn't derive key for path %s", a.Path)
+		}
+	}
+	private, err := key.ECPrivKey()
+	if err != nil {
+		return a, err
+	}
+	a.Private = hex.EncodeToString(crypto.FromECDSA(private.ToECDSA()))
+	// address of the base path (saved for encrypted mnemonic) is not valid for the other indexes
+	a.Public = ""
+	return a, nil
+}
+
+// splitDerivedName splits names like `seed/3` to the name of the HD account and the index.
+func splitDerivedName(name string) (string, int, bool) {
+	ix := strings.LastIndex(name, "/")
+	if ix == -1 {
+		return name, 0, false
+	}
+	index, err := strconv.Atoi(name[ix+1:])
+	if err != nil || index < 0 {
+		return name, 0, false
+	}
+	return name[:ix], index, true
+}
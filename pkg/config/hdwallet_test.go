@@ -0,0 +1,59 @@
+package config
+
+import (
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDeriveAccount(t *testing.T) {
+	a, err := MnemonicAccount("seed", "test test test test test test test test test test test junk", "")
+	require.NoError(t, err)
+
+	first, err := DeriveAccount(a, -1)
+	require.NoError(t, err)
+	require.Equal(t, "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266", first.Address().Hex())
+
+	second, err := DeriveAccount(a, 1)
+	require.NoError(t, err)
+	require.Equal(t, "seed/1", second.Name)
+	require.Equal(t, "0x70997970C51812dc3A010C7d01b50e0d17dc79C8", second.Address().Hex())
+
+	repo := AccountRepo{Accounts: []types.Account{first}, Selected: "seed/1"}
+	current, err := repo.GetCurrentAccount()
+	require.NoError(t, err)
+	require.Equal(t, second.Address(), current.Address())
+}
+
+func TestEncryptMnemonic(t *testing.T) {
+	mnemonic := "test test test test test test test test test test test junk"
+	a, err := MnemonicAccount("seed", mnemonic, "")
+	require.NoError(t, err)
+
+	encrypted, err := EncryptMnemonic(a, "secret", keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err)
+	require.Empty(t, encrypted.Mnemonic)
+	require.NotContains(t, encrypted.EncryptedMnemonic, "junk")
+	require.True(t, encrypted.Encrypted())
+	require.Equal(t, "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266", encrypted.Address().Hex())
+
+	_, err = UnlockAccount(encrypted, "wrong")
+	require.Error(t, err)
+	unlocked, err := UnlockAccount(encrypted, "secret")
+	require.NoError(t, err)
+	require.Equal(t, encrypted.Address(), crypto.PubkeyToAddress(unlocked.PrivateKey().PublicKey))
+
+	t.Setenv(PasswordEnv, "secret")
+	signer, err := NewSigner(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, encrypted.Address(), signer.Address())
+	_, err = signer.SignText([]byte("hello"))
+	require.NoError(t, err)
+
+	repo := AccountRepo{Accounts: []types.Account{encrypted}, Selected: "seed/1"}
+	current, err := repo.GetCurrentAccount()
+	require.NoError(t, err)
+	require.Equal(t, "0x70997970C51812dc3A010C7d01b50e0d17dc79C8", current.Address().Hex())
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/manifoldco/promptui"
+	"github.com/pkg/errors"
+	"os"
+	"strings"
+)
+
+// PasswordEnv is the environment variable which can hold the passphrase of the encrypted accounts.
+const PasswordEnv = "CETH_PASSWORD"
+
+// ReadPassword returns the passphrase from the environment or asks for it interactively.
+func ReadPassword(label string, confirm bool) (string, error) {
+	if pwd, found := os.LookupEnv(PasswordEnv); found {
+		return pwd, nil
+	}
+	prompt := promptui.Prompt{
+		Label: label,
+		Mask:  '*',
+	}
+	pwd, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		prompt.Label = "Repeat " + label
+		repeated, err := prompt.Run()
+		if err != nil {
+			return "", err
+		}
+		if repeated != pwd {
+			return "", errors.New("Passphrases are different")
+		}
+	}
+	return pwd, nil
+}
+
+// EncryptAccount creates a new account where the private key is stored in Web3 Secret Storage format.
+func EncryptAccount(name string, key *ecdsa.PrivateKey, passphrase string, scryptN int, scryptP int) (types.Account, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return types.Account{}, err
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	encrypted, err := keystore.EncryptKey(&keystore.Key{
+		Id:         id,
+		Address:    address,
+		PrivateKey: key,
+	}, passphrase, scryptN, scryptP)
+	if err != nil {
+		return types.Account{}, errors.Wrap(err, "Couldn't encrypt private key")
+	}
+	return types.Account{
+		Name:     name,
+		Public:   address.Hex(),
+		Keystore: string(encrypted),
+	}, nil
+}
+
+// KeystoreAccount creates account from an existing keystore JSON (without decrypting it).
+func KeystoreAccount(name string, content []byte) (types.Account, error) {
+	parsed := struct {
+		Address string `json:"address"`
+	}{}
+	err := json.Unmarshal(content, &parsed)
+	if err != nil {
+		return types.Account{}, errors.Wrap(err, "Keystore file is not a valid JSON")
+	}
+	if parsed.Address == "" {
+		return types.Account{}, errors.New("Keystore file doesn't contain the address")
+	}
+	return types.Account{
+		Name:     name,
+		Public:   common.HexToAddress(parsed.Address).Hex(),
+		Keystore: strings.TrimSpace(string(content)),
+	}, nil
+}
+
+// UnlockAccount decrypts the private key of an encrypted account.
+func UnlockAccount(a types.Account, passphrase string) (types.Account, error) {
+	if !a.Encrypted() {
+		return a, nil
+	}
+	if a.EncryptedMnemonic != "" {
+		unlocked, err := UnlockMnemonic(a, passphrase)
+		if err != nil {
+			return a, err
+		}
+		derived, err := DeriveAccount(unlocked, -1)
+		if err != nil {
+			return a, err
+		}
+		if a.Public != "" && derived.Address() != a.Address() {
+			return a, errors.Errorf("Decrypted mnemonic of account %s belongs to a different address: %s", a.Name, derived.Address())
+		}
+		return derived, nil
+	}
+	key, err := keystore.DecryptKey([]byte(a.Keystore), passphrase)
+	if err != nil {
+		return a, errors.Wrapf(err, "Couldn't decrypt the key of account %s", a.Name)
+	}
+	if a.Public != "" && key.Address != a.Address() {
+		return a, errors.Errorf("Decrypted key of account %s belongs to a different address: %s", a.Name, key.Address)
+	}
+	a.Private = hex.EncodeToString(crypto.FromECDSA(key.PrivateKey))
+	return a, nil
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEncryptUnlockAccount(t *testing.T) {
+	key, err := crypto.HexToECDSA("72e06ca1f2a055a4f531d48616a744ca9e0682c32035fadd9f56d814a9704309")
+	require.NoError(t, err)
+
+	a, err := EncryptAccount("key1", key, "secret", 2, 1)
+	require.NoError(t, err)
+	require.True(t, a.Encrypted())
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), a.Address())
+
+	_, err = UnlockAccount(a, "wrong")
+	require.Error(t, err)
+
+	unlocked, err := UnlockAccount(a, "secret")
+	require.NoError(t, err)
+	require.False(t, unlocked.Encrypted())
+	require.Equal(t, key.D, unlocked.PrivateKey().D)
+
+	imported, err := KeystoreAccount("imported", []byte(a.Keystore))
+	require.NoError(t, err)
+	require.Equal(t, a.Address(), imported.Address())
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/ktr0731/go-fuzzyfinder"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"io/ioutil"
 	"math/big"
@@ -98,6 +99,7 @@ func init() {
 		raw := callCmd.Flags().Bool("raw", false, "Use parameter as raw value")
 		file := callCmd.Flags().StringP("file", "f", "", "File where the data value is read from")
 		value := callCmd.Flags().String("value", "", "Value to send with the transaction")
+		accessList := callCmd.Flags().Bool("access-list", false, "Add access list (eth_createAccessList) to the transaction if it saves gas")
 		callCmd.RunE = func(cmd *cobra.Command, args []string) error {
 			ceth, err := NewCethContext(&Settings)
 			if err != nil {
@@ -112,11 +114,30 @@ func init() {
 			if err != nil {
 				return err
 			}
-			return call(ceth, val, data)
+			return call(ceth, val, data, *accessList)
 		}
 		contractCmd.AddCommand(&callCmd)
 
 	}
+	{
+		cmd := cobra.Command{
+			Use:   "multiquery",
+			Short: "Execute many read-only calls in batches (Multicall3 or JSON-RPC batch)",
+			Long: "Execute many read-only calls in batches. Calls are read from file, one call per line: " +
+				"<contract> <function> <param1> <param2>...",
+			Args: cobra.NoArgs,
+		}
+		file := cmd.Flags().StringP("file", "f", "", "File with the calls (- for standard input)")
+		_ = cmd.MarkFlagRequired("file")
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return multiQuery(ceth, *file, Settings.Format)
+		}
+		contractCmd.AddCommand(&cmd)
+	}
 	{
 		queryCommand := cobra.Command{
 			Use:     "query <function> <param1> <param2> ...",
@@ -141,25 +162,40 @@ func init() {
 	}
 	{
 		logCommand := cobra.Command{
-			Use:     "log",
+			Use:     "log [<event>]",
 			Aliases: []string{"logs"},
-			Short:   "Print out blockchain log entries",
-		}
-		limit := logCommand.Flags().Uint64("limit", 0, "Limit number of blocks user to get events")
-		all := logCommand.Flags().Bool("all", false, "Go back in the time until origin and download all pages one by one")
-		raw := logCommand.Flags().Bool("raw", false, "Print out raw log content even if ABI is specified")
-		format := logCommand.Flags().String("format", "", "Format of the output (empty or csv")
-		topic0 := logCommand.Flags().String("topic0", "", "Filter for topic0")
-		topic1 := logCommand.Flags().String("topic1", "", "Filter for topic1")
-		topic2 := logCommand.Flags().String("topic2", "", "Filter for topic2")
-		topic3 := logCommand.Flags().String("topic3", "", "Filter for topic3")
+			Short:   "Print out blockchain log entries (optionally only the events with the given name)",
+			Args:    cobra.MaximumNArgs(1),
+		}
+		opts := logOptions{}
+		logCommand.Flags().Uint64Var(&opts.limit, "limit", 0, "Limit number of blocks user to get events")
+		logCommand.Flags().BoolVar(&opts.all, "all", false, "Go back in the time until origin and download all pages one by one")
+		logCommand.Flags().BoolVar(&opts.raw, "raw", false, "Print out raw log content even if ABI is specified")
+		logCommand.Flags().StringVar(&opts.format, "format", "", "Format of the output (empty or csv")
+		for i := range opts.topics {
+			logCommand.Flags().StringVar(&opts.topics[i], fmt.Sprintf("topic%d", i), "", fmt.Sprintf("Filter for topic%d", i))
+		}
+		logCommand.Flags().StringArrayVar(&opts.addresses, "address", []string{}, "Address (or alias) of the emitting contract instead of the current one. Can be repeated.")
+		logCommand.Flags().BoolVar(&opts.allContracts, "all-contracts", false, "Query the logs of all the registered contracts of the chain")
+		logCommand.Flags().BoolVar(&opts.chainWide, "chain-wide", false, "Query the logs of any contract (requires event or topic filter)")
+		logCommand.Flags().StringArrayVar(&opts.where, "where", []string{}, "Filter by indexed event argument: name=value or name=value1,value2 (any of the values). Can be repeated.")
+		logCommand.Flags().BoolVarP(&opts.follow, "follow", "f", false, "Follow the new events (until interrupted)")
+		logCommand.Flags().StringVar(&opts.from, "from", "head", "First block of --follow: block number, time (see block at) or head")
+		logCommand.Flags().StringVar(&opts.checkpoint, "checkpoint", "", "File to save the last processed block of --follow (and to resume from)")
+		logCommand.Flags().DurationVar(&opts.pollInterval, "poll-interval", 2*time.Second, "Polling frequency of --follow when the endpoint doesn't support subscriptions (HTTP)")
 
 		logCommand.RunE = func(cmd *cobra.Command, args []string) error {
 			ceth, err := NewCethContext(&Settings)
 			if err != nil {
 				return err
 			}
-			return listLogs(ceth, *limit, *raw, *all, *format, *topic0, *topic1, *topic2, *topic3)
+			if opts.format == "" {
+				opts.format = Settings.Format
+			}
+			if len(args) > 0 {
+				opts.event = args[0]
+			}
+			return listLogs(ceth, opts)
 		}
 		contractCmd.AddCommand(&logCommand)
 
@@ -259,7 +295,7 @@ func code(ceth *Ceth) error {
 	if err != nil {
 		return err
 	}
-	code, err := client.Client.CodeAt(ctx, contract.GetAddress(), nil)
+	code, err := client.CodeAt(ctx, contract.GetAddress(), nil)
 	if err != nil {
 		return err
 	}
@@ -268,7 +304,11 @@ func code(ceth *Ceth) error {
 }
 
 func dataSlot(ceth *Ceth, s string) error {
-	_, contract, client, err := ceth.AccountContractClient()
+	contract, err := ceth.GetCurrentContract()
+	if err != nil {
+		return err
+	}
+	client, err := ceth.GetClient()
 	if err != nil {
 		return err
 	}
@@ -447,17 +487,71 @@ func listContracts(ceth *Ceth, all bool) error {
 	return nil
 }
 
-func listLogs(ceth *Ceth, limit uint64, raw bool, all bool, format string, topic0 string, topic1 string, topic2 string, topic3 string) error {
-	contract, err := ceth.GetCurrentContract()
+func listLogs(ceth *Ceth, opts logOptions) error {
+	c, err := ceth.GetClient()
 	if err != nil {
 		return err
 	}
-	c, err := ceth.GetClient()
+	chainID, err := ceth.getCurrentChainID()
+	if err != nil {
+		return err
+	}
+	contracts, err := logContracts(ceth, opts, chainID)
 	if err != nil {
 		return err
 	}
+	var addresses []common.Address
+	for _, contract := range contracts {
+		addresses = append(addresses, contract.GetAddress())
+	}
+	if len(contracts) == 0 {
+		// chain-wide query: all the registered contracts can be used for decoding
+		contracts = chainContracts(ceth, chainID)
+	}
+
+	topics := logTopics(opts.topics)
+	if opts.event != "" {
+		var abis []abi.ABI
+		for _, contract := range contracts {
+			if contract.Abi == "" {
+				continue
+			}
+			contractAbi, err := contract.GetAbi()
+			if err != nil {
+				return err
+			}
+			abis = append(abis, contractAbi)
+		}
+		event, err := findEventIn(append(abis, standardAbis()...), opts.event)
+		if err != nil {
+			return err
+		}
+		filter, err := eventTopics(ceth, event, opts.where)
+		if err != nil {
+			return err
+		}
+		topics, err = mergeTopics(filter, topics)
+		if err != nil {
+			return err
+		}
+	} else if len(opts.where) > 0 {
+		return errors.New("--where requires the event name")
+	}
+	if len(addresses) == 0 && len(topics) == 0 {
+		return errors.New("Chain-wide log query requires an event or --topic filter")
+	}
 
 	ctx := context.Background()
+	q := ethereum.FilterQuery{
+		Addresses: addresses,
+		Topics:    topics,
+	}
+	printer := newLogPrinter(contracts, opts.raw, opts.format, opts.follow)
+	printer.address = len(addresses) != 1
+
+	if opts.follow {
+		return followLogs(c, q, printer, opts)
+	}
 
 	head, err := c.Client.BlockNumber(ctx)
 	if err != nil {
@@ -466,87 +560,114 @@ func listLogs(ceth *Ceth, limit uint64, raw bool, all bool, format string, topic
 
 	until := head
 	for {
-		q := ethereum.FilterQuery{
-			Addresses: []common.Address{
-				contract.GetAddress(),
-			},
-		}
 		q.ToBlock = big.NewInt(int64(until))
-		if limit != 0 {
-			if limit > until {
+		q.FromBlock = nil
+		if opts.limit != 0 {
+			if opts.limit > until {
 				q.FromBlock = big.NewInt(0)
 			} else {
-				q.FromBlock = big.NewInt(int64(until - limit))
-			}
-		}
-
-		if len(topic0)+len(topic1)+len(topic2)+len(topic3) > 0 {
-			if topic0 != "" {
-				q.Topics[0] = []common.Hash{common.HexToHash(topic0)}
-			}
-			if topic1 != "" {
-				q.Topics[1] = []common.Hash{common.HexToHash(topic1)}
-			}
-			if topic2 != "" {
-				q.Topics[2] = []common.Hash{common.HexToHash(topic2)}
-			}
-			if topic3 != "" {
-				q.Topics[3] = []common.Hash{common.HexToHash(topic3)}
+				q.FromBlock = big.NewInt(int64(until - opts.limit))
 			}
 		}
 
 		logs, err := c.Client.FilterLogs(ctx, q)
 		if err != nil {
-			return errors.Wrap(err, "Couldn't get logs for contract "+contract.Address)
+			return errors.Wrap(err, "Couldn't get logs")
 		}
-		contractAbi, err := contract.GetAbi()
-		if !raw && err == nil {
-
-			indexedEvents := map[common.Hash]abi.Event{}
-			for _, e := range contractAbi.Events {
-				indexedEvents[e.ID] = e
-			}
-
-			for _, l := range logs {
-				event := indexedEvents[l.Topics[0]]
-				item, err := LogAsTypedItem(event, l)
-				if err != nil {
-					return err
-				}
-				err = PrintItem(item, format)
-				if err != nil {
-					return err
-				}
-			}
-
-		} else {
-			for _, l := range logs {
-				i := types.Record{
-					Fields: []types.Field{},
-				}
-				i.AddField("index", l.TxIndex)
-				i.AddField("removed", l.Removed)
-				i.AddField("data", hex.EncodeToString(l.Data))
-
-				for ix, topic := range l.Topics {
-					i.AddField(fmt.Sprintf("topic%d", ix), topic.Hex())
-				}
-				err := PrintItem(types.Item{
-					Record: i,
-				}, format)
-				if err != nil {
-					return err
-				}
+		for _, l := range logs {
+			err = printer.print(l)
+			if err != nil {
+				return err
 			}
 		}
-		until = until - limit - 1
-		if !all {
+		// without limit (or at the origin) everything is downloaded with the first request
+		if !opts.all || q.FromBlock == nil || q.FromBlock.Sign() == 0 {
 			break
 		}
+		until = q.FromBlock.Uint64() - 1
 	}
 	return nil
 }
 
+// logContracts returns the contracts of the log query: the --address values, the registered contracts of the chain
+// (--all-contracts) or the current contract. Empty result means chain-wide query (only without address options).
+func logContracts(ceth *Ceth, opts logOptions, chainID int64) ([]types.Contract, error) {
+	var contracts []types.Contract
+	seen := map[common.Address]bool{}
+	add := func(contract types.Contract) {
+		if !seen[contract.GetAddress()] {
+			seen[contract.GetAddress()] = true
+			contracts = append(contracts, contract)
+		}
+	}
+	if opts.allContracts {
+		for _, contract := range chainContracts(ceth, chainID) {
+			add(contract)
+		}
+		// empty address list would be a chain-wide query
+		if len(contracts) == 0 && len(opts.addresses) == 0 {
+			return nil, errors.Errorf("No contracts are registered for chain %d", chainID)
+		}
+	}
+	for _, a := range opts.addresses {
+		if contract, err := ceth.ContractRepo.GetContract(a); err == nil {
+			add(contract)
+			continue
+		}
+		if a == "" {
+			return nil, errors.New("Address is empty")
+		}
+		address, err := ceth.ResolveAddress(a)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid address "+a)
+		}
+		if contract, found := ceth.ContractRepo.GetContractByAddress(address, chainID); found {
+			add(contract)
+			continue
+		}
+		add(types.Contract{Name: a, Address: address.Hex()})
+	}
+	if len(contracts) > 0 || opts.allContracts || opts.chainWide {
+		return contracts, nil
+	}
+	contract, err := ceth.GetCurrentContract()
+	if err != nil {
+		log.Debug().Err(err).Msg("No contract is selected, querying all logs of the chain")
+		return nil, nil
+	}
+	return []types.Contract{contract}, nil
+}
+
+// chainContracts returns the registered contracts of the chain.
+func chainContracts(ceth *Ceth, chainID int64) []types.Contract {
+	var res []types.Contract
+	all, _ := ceth.ContractRepo.ListContracts()
+	for _, contract := range all {
+		if contract.ChainID == 0 || contract.ChainID == chainID {
+			res = append(res, *contract)
+		}
+	}
+	return res
+}
+
+func rawLogItem(l ethtypes.Log) types.Item {
+	i := types.Item{
+		Record: types.Record{
+			Fields: []types.Field{},
+		},
+	}
+	i.AddField("block", l.BlockNumber)
+	i.AddField("index", l.TxIndex)
+	i.AddField("tx", l.TxHash)
+	i.AddField("removed", l.Removed)
+	i.AddField("data", hex.EncodeToString(l.Data))
+
+	for ix, topic := range l.Topics {
+		i.AddField(fmt.Sprintf("topic%d", ix), topic.Hex())
+	}
+	return i
+}
+
 func LogAsTypedItem(event abi.Event, l ethtypes.Log) (types.Item, error) {
 	i := types.Item{
 		Record: types.Record{
@@ -621,7 +742,7 @@ func query(ceth *Ceth, fs encoding.FunctionSignature, data []byte) error {
 
 	res, err := chainClient.SendQuery(ctx, account.Address(), contract.GetAddress(), chain.WithData{Data: data})
 	if err != nil {
-		return err
+		return decodeRevert(err, contract)
 	}
 
 	if fs.Outputs != nil && len(fs.Outputs) > 0 {
@@ -629,51 +750,53 @@ func query(ceth *Ceth, fs encoding.FunctionSignature, data []byte) error {
 		if err != nil {
 			return err
 		}
-
-		stringResults := make([]string, 0)
-		for _, r := range returned {
-			switch v := r.(type) {
-			case [32]uint8:
-				stringResults = append(stringResults, hex.EncodeToString(v[:]))
-			default:
-				stringResults = append(stringResults, fmt.Sprintf("%s", r))
-
-			}
-
-		}
-		fmt.Println(strings.Join(stringResults, ","))
+		return PrintItem(outputItem(fs.Outputs, returned), ceth.Settings.Format)
 	} else {
 		fmt.Println(hex.EncodeToString(res))
 	}
 	return nil
 }
 
-func call(ceth *Ceth, value *big.Int, data []byte) error {
+// outputItem creates printable item from the returned values. Unnamed outputs are called result (or resultN).
+func outputItem(outputs abi.Arguments, values []interface{}) types.Item {
+	item := types.Item{}
+	for ix, v := range values {
+		name := ""
+		if ix < len(outputs) {
+			name = outputs[ix].Name
+		}
+		if name == "" {
+			name = "result"
+			if len(values) > 1 {
+				name = fmt.Sprintf("result%d", ix)
+			}
+		}
+		item.AddField(name, abiValue(v))
+	}
+	return item
+}
+
+func call(ceth *Ceth, value *big.Int, data []byte, accessList bool) error {
 	ctx := context.Background()
-	account, contract, client, err := ceth.AccountContractClient()
+	signer, contract, client, err := ceth.SignerContractClient()
 	if err != nil {
 		return err
 	}
 
 	to := contract.GetAddress()
-	tx, err := client.SendTransaction(ctx, account, &to, chain.WithData{Data: data}, chain.WithValue{Value: value})
-	if err != nil {
-		return err
+	opts := []interface{}{chain.WithData{Data: data}, chain.WithValue{Value: value}}
+	if accessList {
+		opts, err = withAccessList(ctx, client, signer.Address(), &to, opts)
+		if err != nil {
+			return decodeRevert(err, contract)
+		}
 	}
-
-	chainClient, err := ceth.GetChainClient()
+	tx, err := client.SendTransaction(ctx, signer, &to, opts...)
 	if err != nil {
-		return err
-	}
-	for i := 0; i < 30; i++ {
-		info, err := chainClient.GetTransaction(ctx, tx)
-		if err == nil {
-			return PrintItem(info, "console")
-		}
-		time.Sleep(1 * time.Second)
+		return decodeRevert(err, contract)
 	}
-	return fmt.Errorf("Submitted transaction couldn't been retrieved")
 
+	return waitAndPrintTx(ceth, client, tx)
 }
 
 func deploy(ceth *Ceth, quiet bool, alias *string, value *string, contractFile string, constructorArgs []byte) error {
@@ -692,7 +815,7 @@ func deploy(ceth *Ceth, quiet bool, alias *string, value *string, contractFile s
 	codeData = append(codeData, constructorArgs...)
 	ctx := context.Background()
 
-	account, client, err := ceth.AccountClient()
+	signer, client, err := ceth.SignerClient()
 	if err != nil {
 		return err
 	}
@@ -704,21 +827,19 @@ func deploy(ceth *Ceth, quiet bool, alias *string, value *string, contractFile s
 			return fmt.Errorf("invald number")
 		}
 	}
-	txHash, err := client.SendTransaction(ctx, account, nil, chain.WithData{Data: codeData}, chain.WithValue{Value: v})
+	txHash, err := client.SendTransaction(ctx, signer, nil, chain.WithData{Data: codeData}, chain.WithValue{Value: v})
 	if err != nil {
 		return err
 	}
 
-	var receipt *ethtypes.Receipt
-	for {
-		receipt, err = client.Client.TransactionReceipt(ctx, txHash)
-		if err != nil {
-			time.Sleep(1 * time.Second)
-			continue
-		}
-		break
-
+	res, err := waitTx(ceth, client, txHash)
+	if err != nil {
+		return err
+	}
+	if err := txStatusError(res); err != nil {
+		return err
 	}
+	receipt := res.Receipt
 	if quiet {
 		fmt.Println(receipt.ContractAddress.Hex())
 	} else {
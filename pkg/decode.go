@@ -0,0 +1,152 @@
+package cethacea
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/elek/cethacea/pkg/chain"
+	"github.com/elek/cethacea/pkg/encoding"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"math/big"
+	"reflect"
+)
+
+// candidateMethods collects the methods which can be used to decode calldata: the explicit function signature and
+// the methods of the ABI.
+func candidateMethods(signature string, contractAbi *abi.ABI) ([]abi.Method, error) {
+	var methods []abi.Method
+	if signature != "" {
+		fs, err := encoding.ParseFunctionSignature(signature)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, abi.NewMethod(fs.Name, fs.RawName, abi.Function, "", false, false, fs.Inputs, fs.Outputs))
+	}
+	if contractAbi != nil {
+		for _, m := range contractAbi.Methods {
+			methods = append(methods, m)
+		}
+	}
+	return methods, nil
+}
+
+// abiOfAddress returns the parsed ABI of the registered contract with the given address (if any).
+func abiOfAddress(ceth *Ceth, address common.Address, chainID int64) *abi.ABI {
+	contract, found := ceth.ContractRepo.GetContractByAddress(address, chainID)
+	if !found || contract.Abi == "" {
+		return nil
+	}
+	parsed, err := contract.GetAbi()
+	if err != nil {
+		log.Debug().Err(err).Str("contract", contract.Name).Msg("Couldn't parse ABI")
+		return nil
+	}
+	return &parsed
+}
+
+// decodeRevert decodes the custom error of a reverted call with the ABI of the contract (if available).
+func decodeRevert(err error, contract types.Contract) error {
+	if contract.Abi == "" {
+		return err
+	}
+	contractAbi, abiErr := contract.GetAbi()
+	if abiErr != nil {
+		return err
+	}
+	return chain.DecodeRevertWithAbi(err, contractAbi)
+}
+
+// decodeLog decodes the log with the ABI of the emitting contract. Logs of unknown contracts or events are
+// returned in raw form.
+func decodeLog(ceth *Ceth, l ethtypes.Log, chainID int64) types.Item {
+	if contractAbi := abiOfAddress(ceth, l.Address, chainID); contractAbi != nil && len(l.Topics) > 0 {
+		if event, err := contractAbi.EventByID(l.Topics[0]); err == nil {
+			item, err := LogAsTypedItem(*event, l)
+			if err == nil {
+				item.Fields = append([]types.Field{{Name: "address", Value: l.Address.Hex()}}, item.Fields...)
+				return item
+			}
+			log.Debug().Err(err).Str("event", event.Name).Msg("Couldn't decode log")
+		}
+	}
+	item := rawLogItem(l)
+	item.Fields = append([]types.Field{{Name: "address", Value: l.Address.Hex()}}, item.Fields...)
+	return item
+}
+
+// decodeCallData finds the method based on the 4 bytes selector and unpacks the arguments.
+func decodeCallData(data []byte, methods []abi.Method) (abi.Method, []interface{}, error) {
+	if len(data) < 4 {
+		return abi.Method{}, nil, errors.New("Call data is shorter than the 4 bytes selector")
+	}
+	for _, m := range methods {
+		if bytes.Equal(m.ID, data[:4]) {
+			values, err := m.Inputs.Unpack(data[4:])
+			if err != nil {
+				return m, nil, errors.Wrapf(err, "Couldn't unpack arguments of %s", m.Sig)
+			}
+			return m, values, nil
+		}
+	}
+	return abi.Method{}, nil, errors.Errorf("No method is found for selector %x", data[:4])
+}
+
+// addCallDataFields adds the decoded method and the named arguments to the item. Arguments are prefixed with
+// "input." to avoid collision with the transaction fields (like to or value).
+func addCallDataFields(item *types.Item, method abi.Method, values []interface{}) {
+	item.AddField("method", method.Sig)
+	for ix, v := range values {
+		item.AddField("input."+argumentName(method.Inputs, ix), abiValue(v))
+	}
+}
+
+func argumentName(args abi.Arguments, ix int) string {
+	if ix < len(args) && args[ix].Name != "" {
+		return args[ix].Name
+	}
+	return fmt.Sprintf("arg%d", ix)
+}
+
+// abiValue converts the unpacked ABI values to printable form: checksummed addresses, hex bytes, and tuples / arrays
+// with converted elements.
+func abiValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case common.Address:
+		return value.Hex()
+	case []byte:
+		return hexutil.Encode(value)
+	case *big.Int:
+		return value
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return hexutil.Encode(b)
+		}
+		values := make([]interface{}, rv.Len())
+		for ix := 0; ix < rv.Len(); ix++ {
+			values[ix] = abiValue(rv.Index(ix).Interface())
+		}
+		return values
+	case reflect.Struct:
+		tuple := types.Tuple{}
+		for ix := 0; ix < rv.NumField(); ix++ {
+			field := rv.Type().Field(ix)
+			name := field.Tag.Get("json")
+			if name == "" {
+				name = field.Name
+			}
+			tuple = append(tuple, types.Field{Name: name, Value: abiValue(rv.Field(ix).Interface())})
+		}
+		return tuple
+	}
+	return v
+}
@@ -0,0 +1,60 @@
+package cethacea
+
+import (
+	"github.com/elek/cethacea/pkg/encoding"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+)
+
+func TestDecodeCallData(t *testing.T) {
+	erc20, err := types.Contract{Abi: "erc20"}.GetAbi()
+	require.NoError(t, err)
+
+	to := common.HexToAddress("0x158d2c25ba6107b622f288663f50f53601ab6710")
+	data, err := erc20.Pack("transfer", to, big.NewInt(1000))
+	require.NoError(t, err)
+
+	methods, err := candidateMethods("", &erc20)
+	require.NoError(t, err)
+	method, values, err := decodeCallData(data, methods)
+	require.NoError(t, err)
+	require.Equal(t, "transfer", method.RawName)
+
+	item := types.Item{}
+	addCallDataFields(&item, method, values)
+	require.Equal(t, "transfer(address,uint256)", item.GetString("method"))
+	require.Equal(t, to.Hex(), item.GetString("input."+method.Inputs[0].Name))
+
+	methods, err = candidateMethods("transfer(address,uint256)", nil)
+	require.NoError(t, err)
+	_, values, err = decodeCallData(data, methods)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(1000), values[1])
+
+	_, _, err = decodeCallData([]byte{1, 2, 3, 4}, methods)
+	require.Error(t, err)
+}
+
+func TestOutputItem(t *testing.T) {
+	fs, err := encoding.ParseFunctionSignature("get()(address owner,(uint8 kind,bytes4 tag)[] items,bytes)")
+	require.NoError(t, err)
+
+	owner := common.HexToAddress("0x158d2c25ba6107b622f288663f50f53601ab6710")
+	type Item struct {
+		Kind uint8   `json:"kind"`
+		Tag  [4]byte `json:"tag"`
+	}
+	packed, err := fs.Outputs.Pack(owner, []Item{{Kind: 1, Tag: [4]byte{0xca, 0xfe}}}, []byte{1, 2})
+	require.NoError(t, err)
+	values, err := fs.Outputs.Unpack(packed)
+	require.NoError(t, err)
+
+	item := outputItem(fs.Outputs, values)
+	require.Equal(t, owner.Hex(), item.GetString("owner"))
+	require.Equal(t, "result2", item.Fields[2].Name)
+	require.Equal(t, "0x0102", item.Fields[2].Value)
+	require.Equal(t, `[{"kind":1,"tag":"0xcafe0000"}]`, item.Fields[1].String())
+}
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"github.com/elek/cethacea/pkg/types"
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/shopspring/decimal"
 	"math/big"
-	"strconv"
+	"reflect"
 	"strings"
 )
 
@@ -15,96 +18,243 @@ func FunctionHash(f string) []byte {
 	return crypto.Keccak256Hash([]byte(f)).Bytes()[0:4]
 }
 
+// EncodeArguments packs the command line arguments. Arrays and tuples can be defined with bracket (`[1,2]`,
+// `(alice,100)`) or JSON syntax (`["alice","bob"]`, `{"to":"alice","amount":100}`).
 func EncodeArguments(resolver types.AddressResolver, arguments abi.Arguments, args []string) (res []byte, err error) {
 	typedValues := make([]interface{}, 0)
 	for ix, arg := range args {
+		l := literal{value: arg}
 		switch arguments[ix].Type.T {
-		case abi.StringTy:
-			typedValues = append(typedValues, arg)
-		case abi.AddressTy:
-			addr, err := resolver.ResolveAddress(arg)
+		case abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+			l, err = parseLiteral(arg)
 			if err != nil {
 				return nil, err
 			}
-			typedValues = append(typedValues, addr)
-		case abi.UintTy, abi.IntTy:
-			var bi = new(big.Int)
-			if strings.HasPrefix(arg, "0x") {
-				bi.SetString(arg[2:], 16)
-			} else {
-				var ok bool
-				bi, ok = new(big.Int).SetString(arg, 10)
-				if !ok {
-					return nil, fmt.Errorf("wrong big int format")
-				}
-			}
-			typedValues = append(typedValues, &bi)
-		case abi.BytesTy:
-			val, err := HexToBytes(arg)
+		}
+		value, err := typedValue(resolver, arguments[ix].Type, l)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument %d (%s): %w", ix, arguments[ix].Type.String(), err)
+		}
+		typedValues = append(typedValues, value)
+	}
+
+	bytes, err := arguments.Pack(
+		typedValues...,
+	)
+	return bytes, err
+}
+
+// EncodeTopic converts the value of an indexed event argument to the log topic: value types are padded to 32 bytes,
+// string and bytes values are hashed.
+func EncodeTopic(resolver types.AddressResolver, t abi.Type, arg string) (common.Hash, error) {
+	switch t.T {
+	case abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return common.Hash{}, fmt.Errorf("filtering by %s values is not supported (use the raw topic hash)", t.String())
+	}
+	value, err := typedValue(resolver, t, literal{value: arg})
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("invalid %s value %s: %w", t.String(), arg, err)
+	}
+	if bi, ok := value.(*big.Int); ok && bi.Sign() < 0 {
+		// two's complement (MakeTopics would use the absolute value)
+		return common.BytesToHash(math.U256Bytes(new(big.Int).Set(bi))), nil
+	}
+	topics, err := abi.MakeTopics([]interface{}{value})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return topics[0][0], nil
+}
+
+// typedValue converts the parsed literal to the Go type which is expected by the abi packer.
+func typedValue(resolver types.AddressResolver, t abi.Type, l literal) (interface{}, error) {
+	switch t.T {
+	case abi.SliceTy, abi.ArrayTy:
+		if !l.list || l.keys != nil {
+			return nil, fmt.Errorf("array value is expected instead of %s", l.value)
+		}
+		if t.T == abi.ArrayTy && len(l.items) != t.Size {
+			return nil, fmt.Errorf("array should have %d elements (not %d)", t.Size, len(l.items))
+		}
+		var res reflect.Value
+		if t.T == abi.SliceTy {
+			res = reflect.MakeSlice(t.GetType(), len(l.items), len(l.items))
+		} else {
+			res = reflect.New(t.GetType()).Elem()
+		}
+		for ix, item := range l.items {
+			value, err := typedValue(resolver, *t.Elem, item)
 			if err != nil {
 				return nil, err
 			}
-			size := arguments[ix].Type.Size
-			if size == 0 {
-				size = len(val)
+			res.Index(ix).Set(reflect.ValueOf(value))
+		}
+		return res.Interface(), nil
+	case abi.TupleTy:
+		if !l.list {
+			return nil, fmt.Errorf("tuple value is expected instead of %s", l.value)
+		}
+		items := l.items
+		if l.keys != nil {
+			items = make([]literal, len(t.TupleElems))
+			found := map[string]bool{}
+			for ix, key := range l.keys {
+				field := indexOf(t.TupleRawNames, key)
+				if field == -1 {
+					return nil, fmt.Errorf("tuple has no field %s", key)
+				}
+				items[field] = l.items[ix]
+				found[key] = true
 			}
-			c := make([]byte, size)
-			copy(c[:], val)
-			typedValues = append(typedValues, c)
-		case abi.FixedBytesTy:
-			val, err := HexToBytes(arg)
-			if err != nil {
-				return nil, err
+			for _, name := range t.TupleRawNames {
+				if !found[name] {
+					return nil, fmt.Errorf("tuple field %s is missing", name)
+				}
 			}
-			size := arguments[ix].Type.Size
-			typedValues = append(typedValues, SliceToArray(size, val))
-		case abi.ArrayTy:
-
-			val, err := HexToBytes(arg)
+		}
+		if len(items) != len(t.TupleElems) {
+			return nil, fmt.Errorf("tuple should have %d fields (not %d)", len(t.TupleElems), len(items))
+		}
+		res := reflect.New(t.GetType()).Elem()
+		for ix, item := range items {
+			value, err := typedValue(resolver, *t.TupleElems[ix], item)
 			if err != nil {
 				return nil, err
 			}
-			size := arguments[ix].Type.Size
-			typedValues = append(typedValues, SliceToArray(size, val))
-		case abi.BoolTy:
-			val := false
-			for _, t := range []string{"1", "true"} {
-				if strings.ToLower(arg) == t {
-					val = true
-				}
-			}
-			typedValues = append(typedValues, val)
-		default:
-			return nil, fmt.Errorf("unsupported argument type %v", arguments[ix])
+			res.Field(ix).Set(reflect.ValueOf(value))
 		}
+		return res.Interface(), nil
 	}
 
-	bytes, err := arguments.Pack(
-		typedValues...,
-	)
-	return bytes, err
+	if l.list {
+		return nil, fmt.Errorf("%s value is expected instead of list", t.String())
+	}
+	arg := l.value
+	switch t.T {
+	case abi.StringTy:
+		return arg, nil
+	case abi.AddressTy:
+		return resolver.ResolveAddress(arg)
+	case abi.UintTy, abi.IntTy:
+		bi, err := ParseBigInt(arg)
+		if err != nil {
+			return nil, err
+		}
+		return intValue(t, bi)
+	case abi.BytesTy:
+		return HexToBytes(arg)
+	case abi.FixedBytesTy:
+		val, err := HexToBytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		if len(val) > t.Size {
+			return nil, fmt.Errorf("value is longer than %d bytes", t.Size)
+		}
+		return SliceToArray(t.Size, val), nil
+	case abi.BoolTy:
+		switch strings.ToLower(arg) {
+		case "1", "true":
+			return true, nil
+		case "0", "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid bool value %s", arg)
+	default:
+		return nil, fmt.Errorf("unsupported argument type %s", t.String())
+	}
 }
 
-func SliceToArray(size int, val []byte) interface{} {
-	var c interface{}
-	switch size {
-	case 32:
-		arr := [32]byte{}
-		copy(arr[:], val)
-		c = arr
-	case 16:
-		arr := [16]byte{}
-		copy(arr[:], val)
-		c = arr
-	case 8:
-		arr := [8]byte{}
-		copy(arr[:], val)
-		c = arr
-	default:
-		panic("Unsupported array size " + strconv.Itoa(size))
+// ParseBigInt parses decimal or 0x prefixed hex numbers (with optional minus sign).
+func ParseBigInt(arg string) (*big.Int, error) {
+	s := strings.TrimSpace(arg)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	base := 10
+	if strings.HasPrefix(s, "0x") {
+		s = s[2:]
+		base = 16
+	}
+	bi, ok := new(big.Int).SetString(s, base)
+	if !ok {
+		return nil, fmt.Errorf("wrong big int format: %s", arg)
+	}
+	if negative {
+		bi.Neg(bi)
 	}
+	return bi, nil
+}
+
+// units are the accepted suffixes of ParseAmount with the decimal exponent (gwei should be checked before wei).
+var units = []struct {
+	name     string
+	exponent int32
+}{
+	{"gwei", 9},
+	{"ether", 18},
+	{"eth", 18},
+	{"wei", 0},
+}
 
-	return c
+// ParseAmount parses ether amounts with optional unit (like `1.5gwei`, `0.01 ether`). Numbers without unit are
+// interpreted as wei.
+func ParseAmount(arg string) (*big.Int, error) {
+	s := strings.ToLower(strings.TrimSpace(arg))
+	exp := int32(0)
+	for _, unit := range units {
+		if strings.HasSuffix(s, unit.name) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.name))
+			exp = unit.exponent
+			break
+		}
+	}
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount %s (use number with optional wei, gwei or ether unit)", arg)
+	}
+	d = d.Shift(exp)
+	if d.Sign() < 0 || !d.Equal(d.Truncate(0)) {
+		return nil, fmt.Errorf("invalid amount %s (should be non-negative, integer wei)", arg)
+	}
+	return d.BigInt(), nil
+}
+
+// intValue checks the range of the number and converts it to the Go type of the abi type (uint8, int64, *big.Int...).
+func intValue(t abi.Type, bi *big.Int) (interface{}, error) {
+	if t.T == abi.UintTy {
+		if bi.Sign() < 0 || bi.BitLen() > t.Size {
+			return nil, fmt.Errorf("%s is out of range of %s", bi, t.String())
+		}
+	} else {
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+		if bi.Cmp(limit) >= 0 || bi.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return nil, fmt.Errorf("%s is out of range of %s", bi, t.String())
+		}
+	}
+	goType := t.GetType()
+	switch goType.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.ValueOf(bi.Uint64()).Convert(goType).Interface(), nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(bi.Int64()).Convert(goType).Interface(), nil
+	}
+	return bi, nil
+}
+
+func indexOf(values []string, value string) int {
+	for ix, v := range values {
+		if v == value {
+			return ix
+		}
+	}
+	return -1
+}
+
+// SliceToArray copies the bytes to a fixed size byte array ([size]byte). Shorter values are padded with zeros.
+func SliceToArray(size int, val []byte) interface{} {
+	arr := reflect.New(reflect.ArrayOf(size, reflect.TypeOf(byte(0)))).Elem()
+	reflect.Copy(arr, reflect.ValueOf(val))
+	return arr.Interface()
 }
 
 func HexToBytes(data string) ([]byte, error) {
@@ -0,0 +1,186 @@
+package encoding
+
+import (
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+)
+
+func TestEncodeArguments(t *testing.T) {
+	alice := common.HexToAddress("0x158d2c25ba6107b622f288663f50f53601ab6710")
+	bob := common.HexToAddress("0x8d3637944ca90b47ff2aa814982cf8d81b51f044")
+
+	tests := []struct {
+		signature string
+		args      []string
+		expected  []interface{}
+	}{
+		{
+			signature: "f(uint256[])",
+			args:      []string{"[1, 2, 0x10]"},
+			expected:  []interface{}{[]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(16)}},
+		},
+		{
+			signature: "f(address[2],bytes4,bytes20)",
+			args:      []string{`["` + alice.Hex() + `","` + bob.Hex() + `"]`, "0x01020304", "0x01"},
+			expected: []interface{}{
+				[2]common.Address{alice, bob},
+				[4]byte{1, 2, 3, 4},
+				[20]byte{1},
+			},
+		},
+		{
+			signature: "f(int8,int256,uint8,bool)",
+			args:      []string{"-128", "-0x10", "255", "true"},
+			expected:  []interface{}{int8(-128), big.NewInt(-16), uint8(255), true},
+		},
+		{
+			signature: "f(string,string[])",
+			args:      []string{"hello, world", `["a,b", "c"]`},
+			expected:  []interface{}{"hello, world", []string{"a,b", "c"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.signature, func(t *testing.T) {
+			fs, err := ParseFunctionSignature(tc.signature)
+			require.NoError(t, err)
+
+			encoded, err := EncodeArguments(types.WithoutAddressResolution{}, fs.Inputs, tc.args)
+			require.NoError(t, err)
+
+			expected, err := fs.Inputs.Pack(tc.expected...)
+			require.NoError(t, err)
+			require.Equal(t, expected, encoded)
+		})
+	}
+}
+
+func TestEncodeTuple(t *testing.T) {
+	alice := common.HexToAddress("0x158d2c25ba6107b622f288663f50f53601ab6710")
+	bob := common.HexToAddress("0x8d3637944ca90b47ff2aa814982cf8d81b51f044")
+
+	fs, err := ParseFunctionSignature("submit((address to,uint256 amount)[] payments,uint8)")
+	require.NoError(t, err)
+	require.Equal(t, "submit((address,uint256)[],uint8)", fs.CanonicalName())
+	require.Equal(t, "payments", fs.Inputs[0].Name)
+
+	bracket, err := EncodeArguments(types.WithoutAddressResolution{}, fs.Inputs, []string{
+		"[(" + alice.Hex() + ", 1), (" + bob.Hex() + ", 2)]",
+		"3",
+	})
+	require.NoError(t, err)
+
+	json, err := EncodeArguments(types.WithoutAddressResolution{}, fs.Inputs, []string{
+		`[{"to":"` + alice.Hex() + `","amount":1},{"amount":2,"to":"` + bob.Hex() + `"}]`,
+		"3",
+	})
+	require.NoError(t, err)
+	require.Equal(t, bracket, json)
+
+	payment, err := abi.NewType("tuple[]", "", []abi.ArgumentMarshaling{
+		{Name: "to", Type: "address"},
+		{Name: "amount", Type: "uint256"},
+	})
+	require.NoError(t, err)
+	uint8Type, err := abi.NewType("uint8", "", nil)
+	require.NoError(t, err)
+	type Payment struct {
+		To     common.Address
+		Amount *big.Int
+	}
+	expected, err := abi.Arguments{{Type: payment}, {Type: uint8Type}}.Pack([]Payment{
+		{To: alice, Amount: big.NewInt(1)},
+		{To: bob, Amount: big.NewInt(2)},
+	}, uint8(3))
+	require.NoError(t, err)
+	require.Equal(t, expected, bracket)
+}
+
+func TestEncodeArgumentsErrors(t *testing.T) {
+	for signature, arg := range map[string]string{
+		"f(uint8)":      "256",
+		"f(int8)":       "-129",
+		"f(uint256)":    "-1",
+		"f(address[2])": "[0x01]",
+		"f(bytes2)":     "0x010203",
+		"f(uint256[])":  "[1,2",
+		"f(bool)":       "yes",
+	} {
+		fs, err := ParseFunctionSignature(signature)
+		require.NoError(t, err)
+		_, err = EncodeArguments(types.WithoutAddressResolution{}, fs.Inputs, []string{arg})
+		require.Error(t, err, signature)
+	}
+}
+
+func TestParseFunctionSignatureErrors(t *testing.T) {
+	for _, signature := range []string{
+		"f(,uint256)",
+		"f(uint256,)",
+		"f(uint256, ,address)",
+		"f((uint8,))",
+		"f()uint256,",
+		"f(uint256",
+	} {
+		_, err := ParseFunctionSignature(signature)
+		require.Error(t, err, signature)
+	}
+	fs, err := ParseFunctionSignature("f( )")
+	require.NoError(t, err)
+	require.Empty(t, fs.Inputs)
+}
+
+func TestEncodeTopic(t *testing.T) {
+	fs, err := ParseFunctionSignature("f(address,uint8,int256,string,bytes4,bool,uint256[])")
+	require.NoError(t, err)
+	alice := common.HexToAddress("0x158d2c25ba6107b622f288663f50f53601ab6710")
+
+	tests := []struct {
+		arg      string
+		expected common.Hash
+	}{
+		{alice.Hex(), common.HexToHash("0x000000000000000000000000158d2c25ba6107b622f288663f50f53601ab6710")},
+		{"0x10", common.HexToHash("0x10")},
+		{"-1", common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")},
+		{"hello", crypto.Keccak256Hash([]byte("hello"))},
+		{"0xcafe", common.HexToHash("0xcafe000000000000000000000000000000000000000000000000000000000000")},
+		{"true", common.HexToHash("0x01")},
+	}
+	for ix, tc := range tests {
+		topic, err := EncodeTopic(types.WithoutAddressResolution{}, fs.Inputs[ix].Type, tc.arg)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, topic, tc.arg)
+	}
+
+	_, err = EncodeTopic(types.WithoutAddressResolution{}, fs.Inputs[6].Type, "[1,2]")
+	require.Error(t, err)
+	_, err = EncodeTopic(types.WithoutAddressResolution{}, fs.Inputs[1].Type, "256")
+	require.Error(t, err)
+}
+
+func TestParseAmount(t *testing.T) {
+	for input, expected := range map[string]string{
+		"100":         "100",
+		"100wei":      "100",
+		"1.5gwei":     "1500000000",
+		"2 GWei":      "2000000000",
+		"0.01 ether":  "10000000000000000",
+		"1eth":        "1000000000000000000",
+		"0.000000001": "",
+		"1.5":         "",
+		"-1gwei":      "",
+		"1 finney":    "",
+	} {
+		v, err := ParseAmount(input)
+		if expected == "" {
+			require.Error(t, err, input)
+			continue
+		}
+		require.NoError(t, err, input)
+		require.Equal(t, expected, v.String(), input)
+	}
+}
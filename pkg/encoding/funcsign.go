@@ -32,45 +32,154 @@ func (f *FunctionSignature) EncodeFuncCall(resolver types.AddressResolver, args
 	return c, nil
 }
 
+// ParseFunctionSignature parses signatures like `transfer(address,uint256)bool`. Tuples are defined with
+// parenthesis (`submit((address,uint256)[])`) and arguments can be named (`transfer(address to,uint256 amount)`).
+// Return types can also be wrapped with parenthesis; a single tuple should be returned as `get()((uint8,bytes4))`.
 func ParseFunctionSignature(sign string) (FunctionSignature, error) {
-	nameArg := strings.Split(sign, "(")
-	paramParts := strings.Split(nameArg[1], ")")
-	argumentsString := paramParts[0]
-	argTypes := strings.Split(argumentsString, ",")
-	if argumentsString == "" {
-		argTypes = []string{}
+	open := strings.Index(sign, "(")
+	if open == -1 {
+		return FunctionSignature{}, fmt.Errorf("function signature should contain argument list: %s", sign)
 	}
-	returnTypes := strings.Split(paramParts[1], ",")
-	if paramParts[1] == "" {
-		returnTypes = []string{}
+	closing := matchingParen(sign, open)
+	if closing == -1 {
+		return FunctionSignature{}, fmt.Errorf("unbalanced parenthesis in function signature %s", sign)
 	}
+	name := strings.TrimSpace(sign[:open])
 
-	inputs := abi.Arguments{}
-	for _, argType := range argTypes {
-		ty, err := abi.NewType(argType, "", nil)
+	inputs, err := parseArguments(sign[open+1 : closing])
+	if err != nil {
+		return FunctionSignature{}, err
+	}
+
+	// return types can be listed as is (`get()uint256,address`) or in parenthesis (`get()(uint256,address)`)
+	returns := strings.TrimSpace(sign[closing+1:])
+	if strings.HasPrefix(returns, "(") && matchingParen(returns, 0) == len(returns)-1 {
+		returns = returns[1 : len(returns)-1]
+	}
+	outputs, err := parseArguments(returns)
+	if err != nil {
+		return FunctionSignature{}, err
+	}
+
+	return FunctionSignature{
+		Name:    name,
+		RawName: name,
+		Inputs:  inputs,
+		Outputs: outputs,
+	}, nil
+}
+
+func parseArguments(s string) (abi.Arguments, error) {
+	arguments := abi.Arguments{}
+	for _, argType := range splitTypes(s) {
+		marshaling, err := typeMarshaling(argType)
+		if err != nil {
+			return nil, err
+		}
+		ty, err := abi.NewType(marshaling.Type, "", marshaling.Components)
 		if err != nil {
-			return FunctionSignature{}, err
+			return nil, err
 		}
-		inputs = append(inputs, abi.Argument{
+		arguments = append(arguments, abi.Argument{
+			Name: marshaling.Name,
 			Type: ty,
 		})
 	}
+	return arguments, nil
+}
+
+// ParseType parses a solidity type, including tuples like `(address,uint256)[]`.
+func ParseType(s string) (abi.Type, error) {
+	marshaling, err := typeMarshaling(s)
+	if err != nil {
+		return abi.Type{}, err
+	}
+	return abi.NewType(marshaling.Type, "", marshaling.Components)
+}
+
+// typeMarshaling converts the (optionally named) type definition to the JSON ABI form.
+func typeMarshaling(s string) (abi.ArgumentMarshaling, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") {
+		fields := strings.Fields(s)
+		if len(fields) == 0 {
+			return abi.ArgumentMarshaling{}, fmt.Errorf("empty type in type list")
+		}
+		m := abi.ArgumentMarshaling{
+			Type: fields[0],
+		}
+		if len(fields) > 1 {
+			m.Name = fields[len(fields)-1]
+		}
+		return m, nil
+	}
 
-	outputs := abi.Arguments{}
-	for _, returnType := range returnTypes {
-		ty, err := abi.NewType(returnType, "", nil)
+	closing := matchingParen(s, 0)
+	if closing == -1 {
+		return abi.ArgumentMarshaling{}, fmt.Errorf("unbalanced parenthesis in type %s", s)
+	}
+	m := abi.ArgumentMarshaling{
+		Type: "tuple",
+	}
+	rest := strings.Fields(s[closing+1:])
+	if len(rest) > 0 && strings.HasPrefix(rest[0], "[") {
+		m.Type += rest[0]
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		m.Name = rest[len(rest)-1]
+	}
+	for ix, elem := range splitTypes(s[1:closing]) {
+		component, err := typeMarshaling(elem)
 		if err != nil {
-			return FunctionSignature{}, err
+			return m, err
+		}
+		if component.Name == "" {
+			component.Name = fmt.Sprintf("field%d", ix)
+		}
+		m.Components = append(m.Components, component)
+	}
+	return m, nil
+}
+
+// splitTypes splits comma separated type list, ignoring the commas of the tuples. Empty elements are kept (to be
+// reported by typeMarshaling), except for an empty list.
+func splitTypes(s string) []string {
+	var res []string
+	if strings.TrimSpace(s) == "" {
+		return res
+	}
+	depth := 0
+	start := 0
+	for ix, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				res = append(res, s[start:ix])
+				start = ix + 1
+			}
 		}
-		outputs = append(outputs, abi.Argument{
-			Type: ty,
-		})
 	}
+	return append(res, s[start:])
+}
 
-	return FunctionSignature{
-		Name:    nameArg[0],
-		RawName: nameArg[0],
-		Inputs:  inputs,
-		Outputs: outputs,
-	}, nil
+// matchingParen returns the index of the parenthesis which closes the one at the open position.
+func matchingParen(s string, open int) int {
+	depth := 0
+	for ix := open; ix < len(s); ix++ {
+		switch s[ix] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return ix
+			}
+		}
+	}
+	return -1
 }
@@ -0,0 +1,157 @@
+package encoding
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// literal is a parsed command line value of a composite argument. It's either a scalar value, a list
+// (`[1,2]`, `(alice,2)` or JSON array) or a JSON object (`{"to":"alice","amount":2}`).
+type literal struct {
+	value string
+	list  bool
+	items []literal
+	keys  []string
+}
+
+// parseLiteral parses the bracket / JSON syntax of array and tuple arguments.
+func parseLiteral(s string) (literal, error) {
+	p := literalParser{s: s}
+	l, err := p.parse()
+	if err != nil {
+		return literal{}, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.s) {
+		return literal{}, fmt.Errorf("unexpected character '%c' at position %d of %s", p.s[p.pos], p.pos, s)
+	}
+	return l, nil
+}
+
+type literalParser struct {
+	s   string
+	pos int
+}
+
+func (p *literalParser) skipSpace() {
+	for p.pos < len(p.s) && strings.ContainsRune(" \t\r\n", rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *literalParser) parse() (literal, error) {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return literal{}, fmt.Errorf("unexpected end of value %s", p.s)
+	}
+	switch p.s[p.pos] {
+	case '[':
+		return p.parseList(']')
+	case '(':
+		return p.parseList(')')
+	case '{':
+		return p.parseObject()
+	case '"':
+		value, err := p.parseQuoted()
+		return literal{value: value}, err
+	default:
+		start := p.pos
+		for p.pos < len(p.s) && !strings.ContainsRune(",])}", rune(p.s[p.pos])) {
+			p.pos++
+		}
+		return literal{value: strings.TrimSpace(p.s[start:p.pos])}, nil
+	}
+}
+
+func (p *literalParser) parseList(closing byte) (literal, error) {
+	res := literal{list: true}
+	p.pos++
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == closing {
+		p.pos++
+		return res, nil
+	}
+	for {
+		item, err := p.parse()
+		if err != nil {
+			return res, err
+		}
+		res.items = append(res.items, item)
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return res, fmt.Errorf("missing '%c' in %s", closing, p.s)
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case closing:
+			p.pos++
+			return res, nil
+		default:
+			return res, fmt.Errorf("unexpected character '%c' at position %d of %s", p.s[p.pos], p.pos, p.s)
+		}
+	}
+}
+
+func (p *literalParser) parseObject() (literal, error) {
+	res := literal{list: true}
+	p.pos++
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == '}' {
+		p.pos++
+		res.keys = []string{}
+		return res, nil
+	}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) || p.s[p.pos] != '"' {
+			return res, fmt.Errorf("object keys should be quoted in %s", p.s)
+		}
+		key, err := p.parseQuoted()
+		if err != nil {
+			return res, err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.s) || p.s[p.pos] != ':' {
+			return res, fmt.Errorf("missing ':' after key %s in %s", key, p.s)
+		}
+		p.pos++
+		item, err := p.parse()
+		if err != nil {
+			return res, err
+		}
+		res.keys = append(res.keys, key)
+		res.items = append(res.items, item)
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return res, fmt.Errorf("missing '}' in %s", p.s)
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return res, nil
+		default:
+			return res, fmt.Errorf("unexpected character '%c' at position %d of %s", p.s[p.pos], p.pos, p.s)
+		}
+	}
+}
+
+func (p *literalParser) parseQuoted() (string, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case '"':
+			p.pos++
+			return strconv.Unquote(p.s[start:p.pos])
+		}
+		p.pos++
+	}
+	return "", fmt.Errorf("unterminated string in %s", p.s)
+}
@@ -0,0 +1,276 @@
+package cethacea
+
+import (
+	"context"
+	"fmt"
+	"github.com/elek/cethacea/pkg/chain"
+	"github.com/elek/cethacea/pkg/index"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"math/big"
+	"reflect"
+	"sort"
+)
+
+type indexOptions struct {
+	sqlite        string
+	csv           string
+	from          uint64
+	to            uint64
+	confirmations uint64
+	window        uint64
+}
+
+// eventTable is the table definition and the decoder of one event.
+type eventTable struct {
+	event abi.Event
+	table index.Table
+}
+
+func init() {
+	opts := indexOptions{}
+	cmd := cobra.Command{
+		Use:   "index <contract>",
+		Short: "Download all the events of the contract to a local SQLite database (or CSV files), incrementally",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return indexContract(ceth, args[0], opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.sqlite, "sqlite", "", "SQLite database file (default: <contract>.sqlite)")
+	cmd.Flags().StringVar(&opts.csv, "csv", "", "Directory of the CSV files (one file per event) instead of SQLite")
+	cmd.Flags().Uint64Var(&opts.from, "from", 0, "First block to index (if nothing is indexed yet)")
+	cmd.Flags().Uint64Var(&opts.to, "to", 0, "Last block to index (default: head - confirmations)")
+	cmd.Flags().Uint64Var(&opts.confirmations, "confirmations", 12, "Index only the blocks with this many confirmations")
+	cmd.Flags().Uint64Var(&opts.window, "window", 2000, "Initial number of blocks per eth_getLogs request (adjusted automatically)")
+	RootCmd.AddCommand(&cmd)
+}
+
+func indexContract(ceth *Ceth, name string, opts indexOptions) error {
+	ctx := context.Background()
+	contract, err := ceth.ContractRepo.GetContract(name)
+	if err != nil {
+		return err
+	}
+	contractAbi, err := contract.GetAbi()
+	if err != nil {
+		return errors.Wrap(err, "ABI of the contract is required to index the events")
+	}
+	client, err := ceth.GetClient()
+	if err != nil {
+		return err
+	}
+	chainID, err := client.GetChainID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var store index.Store
+	key := fmt.Sprintf("%d/%s", chainID, contract.GetAddress().Hex())
+	if opts.csv != "" {
+		store, err = index.NewCsvStore(opts.csv, key)
+	} else {
+		if opts.sqlite == "" {
+			opts.sqlite = index.Identifier(name) + ".sqlite"
+		}
+		store, err = index.NewSqliteStore(opts.sqlite, key)
+	}
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	tables := map[common.Hash]eventTable{}
+	for _, e := range contractAbi.Events {
+		if e.Anonymous {
+			continue
+		}
+		t := eventTable{
+			event: e,
+			table: index.NewTable(e.Name, eventColumns(e)),
+		}
+		err = store.CreateTable(t.table)
+		if err != nil {
+			return err
+		}
+		tables[e.ID] = t
+	}
+
+	from := opts.from
+	last, found, err := store.LastBlock()
+	if err != nil {
+		return err
+	}
+	if found {
+		from = last + 1
+	}
+	to := opts.to
+	if to == 0 {
+		head, err := client.Client.BlockNumber(ctx)
+		if err != nil {
+			return err
+		}
+		if head < opts.confirmations {
+			fmt.Printf("Nothing to index yet (head %d, confirmations %d)\n", head, opts.confirmations)
+			return nil
+		}
+		to = head - opts.confirmations
+	}
+	if from > to {
+		if found {
+			fmt.Printf("Index is up to date (block %d, safe head %d)\n", last, to)
+		} else {
+			fmt.Printf("Nothing to index yet (from block %d, safe head %d)\n", from, to)
+		}
+		return nil
+	}
+
+	counts := map[string]int{}
+	q := ethereum.FilterQuery{
+		Addresses: []common.Address{contract.GetAddress()},
+	}
+	window := chain.NewLogWindow(opts.window)
+	err = window.Fetch(ctx, client.Client, q, from, to, func(logs []ethtypes.Log, start uint64, end uint64) error {
+		rows := index.Rows{}
+		for _, l := range logs {
+			if len(l.Topics) == 0 {
+				continue
+			}
+			t, found := tables[l.Topics[0]]
+			if !found {
+				continue
+			}
+			row, err := eventRow(t, l)
+			if err != nil {
+				return errors.Wrapf(err, "Couldn't decode %s event in tx %s", t.event.Name, l.TxHash)
+			}
+			rows[t.table.Name] = append(rows[t.table.Name], row)
+			counts[t.table.Name]++
+		}
+		return store.Write(rows, end)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Indexed blocks %d-%d\n", from, to)
+	var names []string
+	for n := range counts {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Printf("%s %d\n", n, counts[n])
+	}
+	return nil
+}
+
+// eventColumns returns the columns of the event arguments.
+func eventColumns(e abi.Event) []index.Column {
+	var columns []index.Column
+	for ix, input := range e.Inputs {
+		integer := input.Type.T == abi.BoolTy
+		if (input.Type.T == abi.IntTy || input.Type.T == abi.UintTy) && input.Type.Size < 64 {
+			integer = true
+		}
+		if input.Indexed && isHashedTopic(input.Type) {
+			integer = false
+		}
+		columns = append(columns, index.Column{
+			Name:    argumentName(e.Inputs, ix),
+			Integer: integer,
+		})
+	}
+	return columns
+}
+
+// isHashedTopic returns true if the indexed argument is stored as hash (dynamic and composite types).
+func isHashedTopic(t abi.Type) bool {
+	switch t.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return true
+	}
+	return false
+}
+
+// eventRow decodes the log to the values of the table columns.
+func eventRow(t eventTable, l ethtypes.Log) ([]interface{}, error) {
+	row := []interface{}{int64(l.BlockNumber), int64(l.Index), l.TxHash.Hex(), l.Address.Hex()}
+
+	var indexed abi.Arguments
+	for ix, input := range t.event.Inputs {
+		if input.Indexed {
+			// unique name for the unnamed arguments
+			input.Name = fmt.Sprintf("arg%d", ix)
+			if isHashedTopic(input.Type) {
+				// only the hash is available
+				input.Type = abi.Type{T: abi.FixedBytesTy, Size: 32, TupleRawName: "bytes32"}
+			}
+			indexed = append(indexed, input)
+		}
+	}
+	if len(l.Topics)-1 != len(indexed) {
+		return nil, errors.Errorf("Event has %d indexed arguments, log has %d topics", len(indexed), len(l.Topics)-1)
+	}
+	topicValues := map[string]interface{}{}
+	err := abi.ParseTopicsIntoMap(topicValues, indexed, l.Topics[1:])
+	if err != nil {
+		return nil, err
+	}
+	dataValues, err := t.event.Inputs.NonIndexed().UnpackValues(l.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	dataIx := 0
+	for ix, input := range t.event.Inputs {
+		var v interface{}
+		if input.Indexed {
+			v = topicValues[fmt.Sprintf("arg%d", ix)]
+		} else {
+			v = dataValues[dataIx]
+			dataIx++
+		}
+		row = append(row, columnValue(v, t.table.Columns[len(index.BaseColumns)+ix].Integer))
+	}
+	return row, nil
+}
+
+// columnValue converts the decoded ABI value to integer or text.
+func columnValue(v interface{}, integer bool) interface{} {
+	if b, ok := v.(bool); ok {
+		if b {
+			return int64(1)
+		}
+		return int64(0)
+	}
+	if integer {
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return rv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return int64(rv.Uint())
+		}
+	}
+	switch value := abiValue(v).(type) {
+	case *big.Int:
+		return value.String()
+	case string:
+		return value
+	case types.Tuple, []interface{}:
+		f := types.Field{Value: value}
+		return f.String()
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
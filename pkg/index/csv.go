@@ -0,0 +1,126 @@
+package index
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// CsvStore saves each event to a separated CSV file of a directory. The last indexed block is saved to state.json.
+type CsvStore struct {
+	dir    string
+	key    string
+	tables map[string]Table
+}
+
+var _ Store = &CsvStore{}
+
+func NewCsvStore(dir string, key string) (*CsvStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &CsvStore{
+		dir:    dir,
+		key:    key,
+		tables: map[string]Table{},
+	}, nil
+}
+
+func (s *CsvStore) file(table string) string {
+	return filepath.Join(s.dir, table+".csv")
+}
+
+func (s *CsvStore) CreateTable(table Table) error {
+	s.tables[table.Name] = table
+	if _, err := os.Stat(s.file(table.Name)); err == nil {
+		return nil
+	}
+	var header []string
+	for _, c := range table.Columns {
+		header = append(header, c.Name)
+	}
+	return s.append(table.Name, [][]string{header})
+}
+
+func (s *CsvStore) append(table string, lines [][]string) error {
+	f, err := os.OpenFile(s.file(table), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	err = w.WriteAll(lines)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Write appends the rows to the CSV files. Rows of an interrupted write can be duplicated by the next run.
+func (s *CsvStore) Write(rows Rows, lastBlock uint64) error {
+	for name, tableRows := range rows {
+		if _, found := s.tables[name]; !found {
+			return errors.Errorf("Table %s is not created", name)
+		}
+		var lines [][]string
+		for _, row := range tableRows {
+			var line []string
+			for _, v := range row {
+				line = append(line, fmt.Sprintf("%v", v))
+			}
+			lines = append(lines, line)
+		}
+		err := s.append(name, lines)
+		if err != nil {
+			return errors.Wrap(err, "Couldn't write "+s.file(name))
+		}
+	}
+	state, err := s.state()
+	if err != nil {
+		return err
+	}
+	state[s.key] = lastBlock
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.dir, ".state.json.tmp")
+	err = ioutil.WriteFile(tmp, content, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, "state.json"))
+}
+
+func (s *CsvStore) state() (map[string]uint64, error) {
+	state := map[string]uint64{}
+	content, err := ioutil.ReadFile(filepath.Join(s.dir, "state.json"))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(content, &state)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid state file")
+	}
+	return state, nil
+}
+
+func (s *CsvStore) LastBlock() (uint64, bool, error) {
+	state, err := s.state()
+	if err != nil {
+		return 0, false, err
+	}
+	last, found := state[s.key]
+	return last, found, nil
+}
+
+func (s *CsvStore) Close() error {
+	return nil
+}
@@ -0,0 +1,110 @@
+package index
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/pkg/errors"
+	// pure Go driver: released binaries are built without cgo
+	_ "modernc.org/sqlite"
+	"strings"
+)
+
+// SqliteStore saves each event to a table of a SQLite database.
+type SqliteStore struct {
+	db *sql.DB
+	// key identifies the indexed source (chain and contract) in the state table.
+	key    string
+	tables map[string]Table
+}
+
+var _ Store = &SqliteStore{}
+
+func NewSqliteStore(file string, key string) (*SqliteStore, error) {
+	db, err := sql.Open("sqlite", file)
+	if err != nil {
+		return nil, errors.Wrap(err, "Couldn't open database "+file)
+	}
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS index_state (key TEXT PRIMARY KEY, last_block INTEGER NOT NULL)")
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "Couldn't initialize database "+file)
+	}
+	return &SqliteStore{
+		db:     db,
+		key:    key,
+		tables: map[string]Table{},
+	}, nil
+}
+
+func (s *SqliteStore) CreateTable(table Table) error {
+	var columns []string
+	for _, c := range table.Columns {
+		t := "TEXT"
+		if c.Integer {
+			t = "INTEGER"
+		}
+		columns = append(columns, fmt.Sprintf("\"%s\" %s", c.Name, t))
+	}
+	_, err := s.db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS \"%s\" (%s, PRIMARY KEY (block, log_index))", table.Name, strings.Join(columns, ", ")))
+	if err != nil {
+		return errors.Wrap(err, "Couldn't create table "+table.Name)
+	}
+	s.tables[table.Name] = table
+	return nil
+}
+
+func (s *SqliteStore) Write(rows Rows, lastBlock uint64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for name, tableRows := range rows {
+		table, found := s.tables[name]
+		if !found {
+			_ = tx.Rollback()
+			return errors.Errorf("Table %s is not created", name)
+		}
+		var names []string
+		var params []string
+		for _, c := range table.Columns {
+			names = append(names, "\""+c.Name+"\"")
+			params = append(params, "?")
+		}
+		stmt, err := tx.Prepare(fmt.Sprintf("INSERT OR REPLACE INTO \"%s\" (%s) VALUES (%s)", name, strings.Join(names, ", "), strings.Join(params, ", ")))
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		for _, row := range tableRows {
+			_, err = stmt.Exec(row...)
+			if err != nil {
+				_ = stmt.Close()
+				_ = tx.Rollback()
+				return errors.Wrap(err, "Couldn't insert into "+name)
+			}
+		}
+		_ = stmt.Close()
+	}
+	_, err = tx.Exec("INSERT OR REPLACE INTO index_state (key, last_block) VALUES (?, ?)", s.key, lastBlock)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SqliteStore) LastBlock() (uint64, bool, error) {
+	var last uint64
+	err := s.db.QueryRow("SELECT last_block FROM index_state WHERE key = ?", s.key).Scan(&last)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return last, true, nil
+}
+
+func (s *SqliteStore) Close() error {
+	return s.db.Close()
+}
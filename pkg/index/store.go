@@ -0,0 +1,74 @@
+// Package index stores decoded contract events in local SQLite or CSV files.
+package index
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Column is one column of the event table.
+type Column struct {
+	Name string
+	// Integer columns are stored as numbers (others as text).
+	Integer bool
+}
+
+// Table is the definition of the table of one event.
+type Table struct {
+	Name    string
+	Columns []Column
+}
+
+// BaseColumns are the first columns of all the event tables. Block and log index identify the row.
+var BaseColumns = []Column{
+	{Name: "block", Integer: true},
+	{Name: "log_index", Integer: true},
+	{Name: "tx_hash"},
+	{Name: "address"},
+}
+
+// NewTable creates the table definition with the base columns and the event specific columns.
+func NewTable(name string, columns []Column) Table {
+	t := Table{
+		Name: Identifier(name),
+	}
+	used := map[string]bool{}
+	for _, c := range BaseColumns {
+		t.Columns = append(t.Columns, c)
+		used[c.Name] = true
+	}
+	for _, c := range columns {
+		c.Name = Identifier(c.Name)
+		for used[c.Name] {
+			c.Name = "_" + c.Name
+		}
+		used[c.Name] = true
+		t.Columns = append(t.Columns, c)
+	}
+	return t
+}
+
+// Rows are the new rows of the tables (key is the table name).
+type Rows map[string][][]interface{}
+
+// Store saves the rows of the event tables, and the last indexed block.
+type Store interface {
+	// CreateTable creates the table (if it doesn't exist yet).
+	CreateTable(table Table) error
+	// Write saves the rows, together with the last indexed block.
+	Write(rows Rows, lastBlock uint64) error
+	// LastBlock returns the last indexed block (false, if nothing is indexed yet).
+	LastBlock() (uint64, bool, error)
+	Close() error
+}
+
+var invalidChars = regexp.MustCompile("[^a-zA-Z0-9_]")
+
+// Identifier converts the name to a safe table/column/file name.
+func Identifier(name string) string {
+	id := invalidChars.ReplaceAllString(name, "_")
+	if id == "" || strings.ContainsAny(id[:1], "0123456789") {
+		id = "_" + id
+	}
+	return id
+}
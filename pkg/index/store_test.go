@@ -0,0 +1,78 @@
+package index
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewTable(t *testing.T) {
+	table := NewTable("My-Event", []Column{{Name: "block"}, {Name: "value", Integer: true}, {Name: "2nd"}})
+	require.Equal(t, "My_Event", table.Name)
+	var names []string
+	for _, c := range table.Columns {
+		names = append(names, c.Name)
+	}
+	require.Equal(t, []string{"block", "log_index", "tx_hash", "address", "_block", "value", "_2nd"}, names)
+}
+
+func testStore(t *testing.T, open func() (Store, error)) {
+	table := NewTable("Transfer", []Column{{Name: "to"}, {Name: "value"}})
+
+	store, err := open()
+	require.NoError(t, err)
+	require.NoError(t, store.CreateTable(table))
+	_, found, err := store.LastBlock()
+	require.NoError(t, err)
+	require.False(t, found)
+
+	err = store.Write(Rows{"Transfer": {{int64(10), int64(0), "0x01", "0xaa", "0xbb", "100"}}}, 20)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	store, err = open()
+	require.NoError(t, err)
+	require.NoError(t, store.CreateTable(table))
+	last, found, err := store.LastBlock()
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, uint64(20), last)
+
+	err = store.Write(Rows{"Transfer": {{int64(25), int64(3), "0x02", "0xaa", "0xcc", "200"}}}, 30)
+	require.NoError(t, err)
+	last, _, err = store.LastBlock()
+	require.NoError(t, err)
+	require.Equal(t, uint64(30), last)
+
+	require.Error(t, store.Write(Rows{"Approval": {{int64(1)}}}, 31))
+	require.NoError(t, store.Close())
+}
+
+func TestSqliteStore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "index.sqlite")
+	testStore(t, func() (Store, error) {
+		return NewSqliteStore(file, "1/0xaa")
+	})
+
+	db, err := sql.Open("sqlite", file)
+	require.NoError(t, err)
+	defer db.Close()
+	var count int
+	var sum int64
+	require.NoError(t, db.QueryRow("SELECT count(*), sum(block) FROM Transfer").Scan(&count, &sum))
+	require.Equal(t, 2, count)
+	require.Equal(t, int64(35), sum)
+}
+
+func TestCsvStore(t *testing.T) {
+	dir := t.TempDir()
+	testStore(t, func() (Store, error) {
+		return NewCsvStore(dir, "1/0xaa")
+	})
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "Transfer.csv"))
+	require.NoError(t, err)
+	require.Equal(t, "block,log_index,tx_hash,address,to,value\n10,0,0x01,0xaa,0xbb,100\n25,3,0x02,0xaa,0xcc,200\n", string(content))
+}
@@ -0,0 +1,57 @@
+package cethacea
+
+import (
+	"github.com/elek/cethacea/pkg/index"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestEventRow(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(`[{"type":"event","name":"Tagged","inputs":[
+		{"name":"owner","type":"address","indexed":true},
+		{"name":"tag","type":"string","indexed":true},
+		{"name":"","type":"uint256","indexed":false},
+		{"name":"kind","type":"uint8","indexed":false},
+		{"name":"active","type":"bool","indexed":false}]}]`))
+	require.NoError(t, err)
+	event := parsed.Events["Tagged"]
+	e := eventTable{
+		event: event,
+		table: index.NewTable(event.Name, eventColumns(event)),
+	}
+	var integers []bool
+	for _, c := range e.table.Columns[len(index.BaseColumns):] {
+		integers = append(integers, c.Integer)
+	}
+	require.Equal(t, []bool{false, false, false, true, true}, integers)
+	require.Equal(t, "arg2", e.table.Columns[len(index.BaseColumns)+2].Name)
+
+	owner := common.HexToAddress("0x158d2c25ba6107b622f288663f50f53601ab6710")
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(1000), uint8(3), true)
+	require.NoError(t, err)
+	tagHash := crypto.Keccak256Hash([]byte("hello"))
+	l := ethtypes.Log{
+		Address:     owner,
+		Topics:      []common.Hash{event.ID, common.BytesToHash(owner.Bytes()), tagHash},
+		Data:        data,
+		BlockNumber: 12,
+		Index:       4,
+		TxHash:      common.HexToHash("0x01"),
+	}
+	row, err := eventRow(e, l)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{
+		int64(12), int64(4), l.TxHash.Hex(), owner.Hex(),
+		owner.Hex(), tagHash.Hex(), "1000", int64(3), int64(1),
+	}, row)
+
+	l.Topics = l.Topics[:2]
+	_, err = eventRow(e, l)
+	require.Error(t, err)
+}
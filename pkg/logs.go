@@ -0,0 +1,407 @@
+package cethacea
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/elek/cethacea/pkg/chain"
+	"github.com/elek/cethacea/pkg/encoding"
+	"github.com/elek/cethacea/pkg/standards"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type logOptions struct {
+	limit  uint64
+	all    bool
+	raw    bool
+	format string
+	topics [4]string
+	// addresses (or aliases) of the emitting contracts, instead of the current contract
+	addresses    []string
+	allContracts bool
+	chainWide    bool
+	// event (name or signature) and the filter conditions on its indexed arguments (name=value[,value...])
+	event string
+	where []string
+
+	follow       bool
+	from         string
+	checkpoint   string
+	pollInterval time.Duration
+}
+
+// logTopics creates the topic filter from the optional topic values.
+func logTopics(topics [4]string) [][]common.Hash {
+	var res [][]common.Hash
+	for ix, t := range topics {
+		if t == "" {
+			continue
+		}
+		for len(res) <= ix {
+			res = append(res, nil)
+		}
+		res[ix] = []common.Hash{common.HexToHash(t)}
+	}
+	return res
+}
+
+// findEventIn returns the event from the first ABI which defines it.
+func findEventIn(abis []abi.ABI, name string) (abi.Event, error) {
+	for _, contractAbi := range abis {
+		if len(matchEvents(contractAbi, name)) > 0 {
+			return findEvent(contractAbi, name)
+		}
+	}
+	return abi.Event{}, errors.Errorf("No such event in the ABIs: %s", name)
+}
+
+// findEvent returns the event of the ABI by name, or by signature (for overloaded events).
+func findEvent(contractAbi abi.ABI, name string) (abi.Event, error) {
+	matches := matchEvents(contractAbi, name)
+	switch len(matches) {
+	case 0:
+		return abi.Event{}, errors.Errorf("No such event in the ABI: %s", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return abi.Event{}, errors.Errorf("Event %s is overloaded, please use the full signature (like %s)", name, matches[0].Sig)
+	}
+}
+
+// matchEvents returns the events with the given name (raw name) or signature.
+func matchEvents(contractAbi abi.ABI, name string) []abi.Event {
+	if e, found := contractAbi.Events[name]; found {
+		return []abi.Event{e}
+	}
+	var matches []abi.Event
+	for _, e := range contractAbi.Events {
+		if e.Sig == name || e.RawName == name {
+			matches = append(matches, e)
+		}
+	}
+	return matches
+}
+
+// eventTopics creates the topic filter of the event. Where conditions (name=value[,value...]) filter the indexed
+// arguments: values of the same argument are OR-ed, different arguments are AND-ed.
+func eventTopics(resolver types.AddressResolver, event abi.Event, where []string) ([][]common.Hash, error) {
+	topics := [][]common.Hash{{event.ID}}
+	for _, w := range where {
+		parts := strings.SplitN(w, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("Invalid condition %s (should be name=value)", w)
+		}
+		position := 0
+		var argument *abi.Argument
+		for ix, input := range event.Inputs {
+			if input.Indexed {
+				position++
+			}
+			if argumentName(event.Inputs, ix) == parts[0] {
+				argument = &event.Inputs[ix]
+				break
+			}
+		}
+		if argument == nil {
+			return nil, errors.Errorf("Event %s has no argument %s", event.Name, parts[0])
+		}
+		if !argument.Indexed {
+			return nil, errors.Errorf("Argument %s of %s is not indexed, it can't be filtered", parts[0], event.Name)
+		}
+		for len(topics) <= position {
+			topics = append(topics, nil)
+		}
+		for _, value := range strings.Split(parts[1], ",") {
+			topic, err := encoding.EncodeTopic(resolver, argument.Type, strings.TrimSpace(value))
+			if err != nil {
+				return nil, errors.Wrapf(err, "Invalid value of %s", parts[0])
+			}
+			topics[position] = append(topics[position], topic)
+		}
+	}
+	return topics, nil
+}
+
+// mergeTopics combines two topic filters. The same topic can be filtered only by one of them.
+func mergeTopics(a [][]common.Hash, b [][]common.Hash) ([][]common.Hash, error) {
+	res := append([][]common.Hash{}, a...)
+	for ix, t := range b {
+		if t == nil {
+			continue
+		}
+		for len(res) <= ix {
+			res = append(res, nil)
+		}
+		if res[ix] != nil {
+			return nil, errors.Errorf("topic%d is filtered twice", ix)
+		}
+		res[ix] = t
+	}
+	return res, nil
+}
+
+// logPrinter prints the log entries, decoded with the ABI of the emitting contract (if available) or with the
+// standard ABIs.
+type logPrinter struct {
+	abis map[common.Address]abi.ABI
+	// standards are the events of the bundled standard ABIs, to decode the logs of unknown contracts.
+	standards []abi.Event
+	raw       bool
+	format    string
+	// lines prints JSON output as line-delimited JSON.
+	lines bool
+	// address adds the emitting contract to the output (logs of multiple contracts).
+	address bool
+}
+
+func newLogPrinter(contracts []types.Contract, raw bool, format string, lines bool) *logPrinter {
+	p := &logPrinter{
+		abis:    map[common.Address]abi.ABI{},
+		raw:     raw,
+		format:  format,
+		lines:   lines,
+		address: len(contracts) != 1,
+	}
+	for _, contract := range contracts {
+		if contract.Abi == "" {
+			continue
+		}
+		contractAbi, err := contract.GetAbi()
+		if err != nil {
+			log.Debug().Err(err).Str("contract", contract.Name).Msg("Couldn't parse ABI")
+			continue
+		}
+		p.abis[contract.GetAddress()] = contractAbi
+	}
+	for _, contractAbi := range standardAbis() {
+		for _, e := range contractAbi.Events {
+			p.standards = append(p.standards, e)
+		}
+	}
+	return p
+}
+
+// standardAbis returns the parsed bundled ABIs.
+func standardAbis() []abi.ABI {
+	var res []abi.ABI
+	for _, name := range standards.Names {
+		contractAbi, err := types.Contract{Abi: name}.GetAbi()
+		if err != nil {
+			log.Debug().Err(err).Str("abi", name).Msg("Couldn't parse standard ABI")
+			continue
+		}
+		res = append(res, contractAbi)
+	}
+	return res
+}
+
+// decode decodes the log with the ABI of the emitting contract, or with the standard event of the same signature.
+func (p *logPrinter) decode(l ethtypes.Log) (types.Item, bool) {
+	if p.raw || len(l.Topics) == 0 {
+		return types.Item{}, false
+	}
+	if contractAbi, found := p.abis[l.Address]; found {
+		if event, err := contractAbi.EventByID(l.Topics[0]); err == nil {
+			item, err := LogAsTypedItem(*event, l)
+			if err == nil {
+				return item, true
+			}
+			log.Debug().Err(err).Str("event", event.Name).Msg("Couldn't decode log")
+		}
+	}
+	for _, event := range p.standards {
+		// events with the same signature can have different indexed arguments (ERC-20 / ERC-721 Transfer)
+		if event.ID != l.Topics[0] || indexedCount(event) != len(l.Topics)-1 {
+			continue
+		}
+		item, err := LogAsTypedItem(event, l)
+		if err == nil {
+			return item, true
+		}
+	}
+	return types.Item{}, false
+}
+
+func indexedCount(event abi.Event) int {
+	count := 0
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			count++
+		}
+	}
+	return count
+}
+
+func (p *logPrinter) item(l ethtypes.Log) types.Item {
+	item, found := p.decode(l)
+	if !found {
+		item = rawLogItem(l)
+	}
+	if p.address {
+		item.Fields = append([]types.Field{{Name: "address", Value: l.Address.Hex()}}, item.Fields...)
+	}
+	return item
+}
+
+func (p *logPrinter) print(l ethtypes.Log) error {
+	if p.lines && p.format == "json" {
+		return PrintJSONLine(p.item(l))
+	}
+	return PrintItem(p.item(l), p.format)
+}
+
+// logCheckpoint is the last processed block of the log follower.
+type logCheckpoint struct {
+	Block uint64 `json:"block"`
+	Hash  string `json:"hash,omitempty"`
+	// Recent are the hashes of the last processed blocks (to find the common ancestor after a reorg).
+	Recent map[uint64]string `json:"recent,omitempty"`
+}
+
+// processed returns the known hashes of the processed blocks.
+func (c logCheckpoint) processed() map[uint64]common.Hash {
+	res := map[uint64]common.Hash{}
+	for n, hash := range c.Recent {
+		res[n] = common.HexToHash(hash)
+	}
+	if c.Hash != "" {
+		res[c.Block] = common.HexToHash(c.Hash)
+	}
+	return res
+}
+
+func loadCheckpoint(file string) (*logCheckpoint, error) {
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &logCheckpoint{}
+	err = json.Unmarshal(content, c)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid checkpoint file "+file)
+	}
+	return c, nil
+}
+
+func saveCheckpoint(file string, c logCheckpoint) error {
+	content, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(file), "."+filepath.Base(file)+".tmp")
+	err = ioutil.WriteFile(tmp, content, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// followLogs prints the events from the start block (or checkpoint) and follows the new blocks until interrupted.
+func followLogs(client *chain.Eth, q ethereum.FilterQuery, printer *logPrinter, opts logOptions) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	follower := chain.NewLogFollower(client.Client, q)
+	follower.Watcher.PollInterval = opts.pollInterval
+	if opts.limit != 0 {
+		follower.Window = chain.NewLogWindow(opts.limit)
+	}
+	handler := func(logs []ethtypes.Log, processed uint64, hash common.Hash) error {
+		for _, l := range logs {
+			err := printer.print(l)
+			if err != nil {
+				return err
+			}
+		}
+		if opts.checkpoint == "" {
+			return nil
+		}
+		c := logCheckpoint{Block: processed, Recent: map[uint64]string{}}
+		if hash != (common.Hash{}) {
+			c.Hash = hash.Hex()
+		}
+		for n, h := range follower.Processed() {
+			c.Recent[n] = h.Hex()
+		}
+		return saveCheckpoint(opts.checkpoint, c)
+	}
+
+	from, err := followStart(ctx, client, follower, handler, opts)
+	if err != nil {
+		return err
+	}
+
+	err = follower.Follow(ctx, from, handler)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// followStart returns the first block to process: the block after the checkpoint, or the one defined by --from.
+// If the checkpoint is not part of the canonical chain any more (reorg), the follower is rewound to the common
+// ancestor, and the logs of the orphaned blocks are handled as removed.
+func followStart(ctx context.Context, client *chain.Eth, follower *chain.LogFollower, handler chain.LogHandler, opts logOptions) (uint64, error) {
+	if opts.checkpoint != "" {
+		c, err := loadCheckpoint(opts.checkpoint)
+		if err != nil {
+			return 0, err
+		}
+		if c != nil {
+			processed := c.processed()
+			if len(processed) == 0 {
+				log.Debug().Uint64("block", c.Block).Msg("Resuming from checkpoint")
+				return c.Block + 1, nil
+			}
+			from, err := follower.Rewind(ctx, processed, handler)
+			if err != nil {
+				return 0, err
+			}
+			last := uint64(0)
+			for n := range processed {
+				if n > last {
+					last = n
+				}
+			}
+			if from > last {
+				from = c.Block + 1
+			} else {
+				log.Warn().Uint64("block", c.Block).Uint64("from", from).Msg("Checkpoint block is not part of the canonical chain any more (reorg), rewinding")
+			}
+			log.Debug().Uint64("block", from).Msg("Resuming from checkpoint")
+			return from, nil
+		}
+	}
+	switch {
+	case opts.from == "" || opts.from == "head":
+		head, err := client.Client.BlockNumber(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return head + 1, nil
+	default:
+		if number, err := strconv.ParseUint(opts.from, 10, 64); err == nil {
+			return number, nil
+		}
+		at, err := parseTime(opts.from)
+		if err != nil {
+			return 0, err
+		}
+		return chain.BlockAtTime(ctx, client.Client, at)
+	}
+}
@@ -0,0 +1,100 @@
+package cethacea
+
+import (
+	"github.com/elek/cethacea/pkg/config"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+)
+
+func TestLogTopics(t *testing.T) {
+	require.Nil(t, logTopics([4]string{}))
+	require.Equal(t, [][]common.Hash{nil, nil, {common.HexToHash("0x02")}}, logTopics([4]string{"", "", "0x02", ""}))
+	require.Equal(t, [][]common.Hash{{common.HexToHash("0x01")}}, logTopics([4]string{"0x01", "", "", ""}))
+}
+
+func TestEventTopics(t *testing.T) {
+	erc20, err := types.Contract{Abi: "erc20"}.GetAbi()
+	require.NoError(t, err)
+	event, err := findEvent(erc20, "Transfer")
+	require.NoError(t, err)
+	_, err = findEvent(erc20, "Mint")
+	require.Error(t, err)
+
+	alice := common.HexToAddress("0x158d2c25ba6107b622f288663f50f53601ab6710")
+	bob := common.HexToAddress("0x8d3637944ca90b47ff2aa814982cf8d81b51f044")
+	topics, err := eventTopics(types.WithoutAddressResolution{}, event, []string{"to=" + alice.Hex() + "," + bob.Hex()})
+	require.NoError(t, err)
+	require.Equal(t, [][]common.Hash{{event.ID}, nil, {common.BytesToHash(alice.Bytes()), common.BytesToHash(bob.Bytes())}}, topics)
+
+	topics, err = eventTopics(types.WithoutAddressResolution{}, event, []string{"from=" + alice.Hex(), "from=" + bob.Hex()})
+	require.NoError(t, err)
+	require.Equal(t, [][]common.Hash{{event.ID}, {common.BytesToHash(alice.Bytes()), common.BytesToHash(bob.Bytes())}}, topics)
+
+	_, err = eventTopics(types.WithoutAddressResolution{}, event, []string{"value=1"})
+	require.Error(t, err)
+	_, err = eventTopics(types.WithoutAddressResolution{}, event, []string{"owner=" + alice.Hex()})
+	require.Error(t, err)
+
+	merged, err := mergeTopics(topics, logTopics([4]string{"", "", "0x02", ""}))
+	require.NoError(t, err)
+	require.Equal(t, []common.Hash{common.HexToHash("0x02")}, merged[2])
+	_, err = mergeTopics(topics, logTopics([4]string{"0x01", "", "", ""}))
+	require.Error(t, err)
+}
+
+func TestLogPrinterStandards(t *testing.T) {
+	token := common.HexToAddress("0x01")
+	nft := common.HexToAddress("0x02")
+	alice := common.HexToAddress("0x158d2c25ba6107b622f288663f50f53601ab6710")
+	bob := common.HexToAddress("0x8d3637944ca90b47ff2aa814982cf8d81b51f044")
+	transfer := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+	printer := newLogPrinter(nil, false, "", false)
+	item := printer.item(ethtypes.Log{
+		Address: token,
+		Topics:  []common.Hash{transfer, common.BytesToHash(alice.Bytes()), common.BytesToHash(bob.Bytes())},
+		Data:    common.BigToHash(big.NewInt(100)).Bytes(),
+	})
+	require.Equal(t, "100", fieldString(item, "value"))
+
+	item = printer.item(ethtypes.Log{
+		Address: nft,
+		Topics:  []common.Hash{transfer, common.BytesToHash(alice.Bytes()), common.BytesToHash(bob.Bytes()), common.BigToHash(big.NewInt(7))},
+	})
+	require.Equal(t, "7", fieldString(item, "tokenId"))
+
+	printer.raw = true
+	item = printer.item(ethtypes.Log{Address: nft, Topics: []common.Hash{transfer}})
+	require.Equal(t, transfer.Hex(), item.GetString("topic0"))
+}
+
+func fieldString(item types.Item, name string) string {
+	for _, f := range item.Fields {
+		if f.Name == name {
+			return f.String()
+		}
+	}
+	return ""
+}
+
+func TestLogContracts(t *testing.T) {
+	token := &types.Contract{Name: "token", Address: "0x158d2c25ba6107b622f288663f50f53601ab6710", ChainID: 5}
+	ceth := &Ceth{ContractRepo: &config.ContractRepo{Contracts: []*types.Contract{token}}}
+
+	contracts, err := logContracts(ceth, logOptions{allContracts: true}, 5)
+	require.NoError(t, err)
+	require.Len(t, contracts, 1)
+
+	// no registered contracts: shouldn't be a chain-wide query
+	_, err = logContracts(ceth, logOptions{allContracts: true}, 1)
+	require.Error(t, err)
+
+	contracts, err = logContracts(ceth, logOptions{chainWide: true}, 1)
+	require.NoError(t, err)
+	require.Empty(t, contracts)
+}
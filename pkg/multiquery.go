@@ -0,0 +1,193 @@
+package cethacea
+
+import (
+	"bufio"
+	"context"
+	"github.com/elek/cethacea/pkg/chain"
+	"github.com/elek/cethacea/pkg/encoding"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// batchedQuery is one line of the multiquery input file.
+type batchedQuery struct {
+	contract string
+	fs       encoding.FunctionSignature
+	call     chain.QueryCall
+}
+
+func multiQuery(ceth *Ceth, file string, format string) error {
+	ctx := context.Background()
+
+	var in io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return errors.Wrap(err, "Couldn't open call file "+file)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	queries, err := readQueries(ceth, in)
+	if err != nil {
+		return err
+	}
+
+	account, err := ceth.AccountRepo.GetCurrentAccount()
+	if err != nil {
+		return err
+	}
+
+	client, err := ceth.GetClient()
+	if err != nil {
+		return err
+	}
+
+	var calls []chain.QueryCall
+	for _, q := range queries {
+		calls = append(calls, q.call)
+	}
+	mc := client.Multicall()
+	mc.From = account.Address()
+	results, err := mc.Query(ctx, calls)
+	if err != nil {
+		return err
+	}
+
+	for ix, q := range queries {
+		item := types.Item{}
+		item.AddField("contract", q.contract)
+		item.AddField("function", q.fs.CanonicalName())
+		if results[ix].Err != nil {
+			item.AddField("error", decodeRevert(results[ix].Err, contractOf(ceth, q.contract)).Error())
+		} else if len(q.fs.Outputs) > 0 {
+			values, err := q.fs.Outputs.Unpack(results[ix].Data)
+			if err != nil {
+				item.AddField("error", err.Error())
+			} else {
+				item.Fields = append(item.Fields, outputItem(q.fs.Outputs, values).Fields...)
+			}
+		} else {
+			item.AddField("result", hexutil.Encode(results[ix].Data))
+		}
+		err = PrintItem(item, format)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readQueries(ceth *Ceth, in io.Reader) ([]batchedQuery, error) {
+	var queries []batchedQuery
+	scanner := bufio.NewScanner(in)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields, err := splitArgs(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid call in line %d", lineNo)
+		}
+		if len(fields) < 2 {
+			return nil, errors.Errorf("Line %d should contain the contract and the function", lineNo)
+		}
+		q, err := parseQuery(ceth, fields[0], fields[1], fields[2:])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid call in line %d", lineNo)
+		}
+		queries = append(queries, q)
+	}
+	return queries, scanner.Err()
+}
+
+// parseQuery encodes one call. Function can be a full signature or a method name of the contract ABI.
+func parseQuery(ceth *Ceth, contract string, function string, args []string) (batchedQuery, error) {
+	q := batchedQuery{
+		contract: contract,
+	}
+	target, err := ceth.ResolveAddress(contract)
+	if err != nil {
+		return q, err
+	}
+	if strings.Contains(function, "(") {
+		q.fs, err = encoding.ParseFunctionSignature(function)
+		if err != nil {
+			return q, err
+		}
+	} else {
+		c, err := ceth.ContractRepo.GetContract(contract)
+		if err != nil {
+			return q, errors.Wrap(err, "ABI is required to call method by name")
+		}
+		contractAbi, err := c.GetAbi()
+		if err != nil {
+			return q, err
+		}
+		method, found := contractAbi.Methods[function]
+		if !found {
+			return q, errors.Errorf("No such method %s in abi %s", function, c.Abi)
+		}
+		q.fs = encoding.FunctionSignature(method)
+	}
+	data, err := q.fs.EncodeFuncCall(ceth, args...)
+	if err != nil {
+		return q, err
+	}
+	q.call = chain.QueryCall{
+		Target: target,
+		Data:   data,
+	}
+	return q, nil
+}
+
+func contractOf(ceth *Ceth, name string) types.Contract {
+	c, err := ceth.ContractRepo.GetContract(name)
+	if err != nil {
+		return types.Contract{}
+	}
+	return c
+}
+
+// splitArgs splits the line by whitespaces, except the single or double quoted parts.
+func splitArgs(line string) ([]string, error) {
+	var res []string
+	current := strings.Builder{}
+	inArg := false
+	var quote rune
+	for _, c := range line {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t':
+			if inArg {
+				res = append(res, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.Errorf("Unterminated quote in %s", line)
+	}
+	if inArg {
+		res = append(res, current.String())
+	}
+	return res, nil
+}
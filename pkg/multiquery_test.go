@@ -0,0 +1,15 @@
+package cethacea
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	args, err := splitArgs(`usdc  'submit((address,uint256)[])' '[(alice, 1)]' "a b"`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"usdc", "submit((address,uint256)[])", "[(alice, 1)]", "a b"}, args)
+
+	_, err = splitArgs(`usdc 'balanceOf(address)`)
+	require.Error(t, err)
+}
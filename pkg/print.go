@@ -1,9 +1,11 @@
 package cethacea
 
 import (
+	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"github.com/elek/cethacea/pkg/chain"
 	"github.com/elek/cethacea/pkg/types"
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/olekukonko/tablewriter"
@@ -12,7 +14,7 @@ import (
 	"os"
 	"reflect"
 	"strconv"
-	"strings"
+	"time"
 )
 
 func PrintTransaction(tx *ethtypes.Transaction, receipt *ethtypes.Receipt) {
@@ -84,6 +86,20 @@ func PrintStruct(str interface{}, format string) error {
 	return nil
 }
 
+// PrintJSONLine prints the item as one line JSON (line-delimited JSON for streaming output).
+func PrintJSONLine(item types.Item) error {
+	res := make(map[string]interface{})
+	for _, record := range item.Record.Fields {
+		res[record.Name] = record.Value
+	}
+	bytes, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(bytes))
+	return nil
+}
+
 func PrintItem(item types.Item, format string) error {
 	maxKeyLength := 0
 	switch format {
@@ -102,7 +118,13 @@ func PrintItem(item types.Item, format string) error {
 		for _, record := range item.Record.Fields {
 			line = append(line, record.String())
 		}
-		fmt.Println(strings.Join(line, ","))
+		w := csv.NewWriter(os.Stdout)
+		err := w.Write(line)
+		if err != nil {
+			return err
+		}
+		w.Flush()
+		return w.Error()
 	case "table":
 		table := tablewriter.NewWriter(os.Stdout)
 		for _, record := range item.Record.Fields {
@@ -134,3 +156,28 @@ func wrap(s string, i int) string {
 	}
 	return s[0:i] + "\n" + wrap(s[i:], i)
 }
+
+// PrintRequestStats prints the number and latency of the JSON-RPC requests (per method) to the standard error.
+func PrintRequestStats() {
+	stats := chain.RequestMetrics.Stats()
+	if len(stats) == 0 {
+		return
+	}
+	table := tablewriter.NewWriter(os.Stderr)
+	table.SetHeader([]string{"method", "calls", "requests", "errors", "total", "avg", "max"})
+	calls := 0
+	for _, s := range stats {
+		table.Append([]string{
+			s.Method,
+			strconv.Itoa(s.Calls),
+			strconv.Itoa(s.Requests),
+			strconv.Itoa(s.Errors),
+			s.Total.Round(time.Millisecond).String(),
+			(s.Total / time.Duration(s.Requests)).Round(time.Millisecond).String(),
+			s.Max.Round(time.Millisecond).String(),
+		})
+		calls += s.Calls
+	}
+	table.SetFooter([]string{"total", strconv.Itoa(calls), strconv.Itoa(chain.RequestMetrics.RoundTrips()), "", "", "", ""})
+	table.Render()
+}
@@ -3,6 +3,7 @@ package cethacea
 import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"time"
 )
 
 var Settings CethSettings
@@ -18,10 +19,16 @@ func init() {
 	RootCmd.PersistentFlags().StringVar(&Settings.Abi, "abi", "", "Override the ABI of the current contract.")
 	RootCmd.PersistentFlags().StringVar(&Settings.Format, "format", "console", "Format of the output (when applicable): console (default), json")
 	RootCmd.PersistentFlags().BoolVar(&Settings.All, "all", false, "Use all chains/contracts/accounts including predefined and the ones from the other chains")
-	RootCmd.PersistentFlags().BoolVar(&Settings.Debug, "debug", false, "Turn on debug level logging")
+	RootCmd.PersistentFlags().BoolVar(&Settings.Debug, "debug", false, "Turn on debug level logging (and print JSON-RPC request statistics)")
 	RootCmd.PersistentFlags().BoolVar(&Settings.Confirm, "confirm", false, "Confirm transactions before send")
-	RootCmd.PersistentFlags().StringVar(&Settings.GasTipCap, "tip", "", "The gas tip to be paid (default: auto)")
+	RootCmd.PersistentFlags().BoolVar(&Settings.NoCache, "no-cache", false, "Don't use the local cache of immutable chain data (blocks, receipts, code, token metadata)")
+	RootCmd.PersistentFlags().StringVar(&Settings.GasTipCap, "tip", "", "The gas tip to be paid, like 1.5gwei (default: based on the fee strategy)")
+	RootCmd.PersistentFlags().StringVar(&Settings.MaxFee, "max-fee", "", "Max fee per gas, like 30gwei (default: twice of the base fee + tip)")
+	RootCmd.PersistentFlags().StringVar(&Settings.FeeStrategy, "fee-strategy", "normal", "Tip based on the recent blocks: slow, normal or fast (10th, 50th, 90th percentile), or custom (--tip and --max-fee)")
+	RootCmd.PersistentFlags().StringVar(&Settings.MaxCost, "max-cost", "", "Abort if the maximum transaction fee (gas * max fee) is higher, like 0.01ether")
 	RootCmd.PersistentFlags().Uint64Var(&Settings.Gas, "gas", 0, "Gas to be used for the transaction. Use 0 (default) to auto-estimate...")
+	RootCmd.PersistentFlags().Uint64Var(&Settings.WaitConfirmations, "wait-confirmations", 1, "Number of confirmations to wait for after sending a transaction")
+	RootCmd.PersistentFlags().DurationVar(&Settings.Timeout, "timeout", 5*time.Minute, "Maximum time to wait for a sent transaction (0: no limit)")
 	_ = viper.BindPFlag("account", RootCmd.PersistentFlags().Lookup("account"))
 	_ = viper.BindPFlag("contract", RootCmd.PersistentFlags().Lookup("contract"))
 	_ = viper.BindPFlag("chain", RootCmd.PersistentFlags().Lookup("chain"))
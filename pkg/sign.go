@@ -0,0 +1,351 @@
+package cethacea
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/elek/cethacea/pkg/encoding"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"strconv"
+)
+
+func init() {
+
+	signCmd := cobra.Command{
+		Use:   "sign",
+		Short: "Sign messages and structured data with the current account",
+	}
+	verifyCmd := cobra.Command{
+		Use:   "verify",
+		Short: "Verify signatures of messages and structured data",
+	}
+	{
+		cmd := cobra.Command{
+			Use:   "typed <file.json>",
+			Short: "Sign EIP-712 typed data",
+			Args:  cobra.ExactArgs(1),
+		}
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return signTyped(ceth, args[0])
+		}
+		signCmd.AddCommand(&cmd)
+	}
+	{
+		cmd := cobra.Command{
+			Use:   "typed <file.json> <signature>",
+			Short: "Verify signature of EIP-712 typed data",
+			Args:  cobra.ExactArgs(2),
+		}
+		address := cmd.Flags().String("address", "", "Expected signer (default: current account)")
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return verifyTyped(ceth, args[0], args[1], *address)
+		}
+		verifyCmd.AddCommand(&cmd)
+	}
+	{
+		cmd := cobra.Command{
+			Use:     "personal <message>",
+			Aliases: []string{"text", "message"},
+			Short:   "Sign message with the EIP-191 prefix (personal_sign)",
+			Args:    cobra.ExactArgs(1),
+		}
+		input := cmd.Flags().String("input", "string", "The interpretation of the message (string,hex)")
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return signPersonal(ceth, args[0], *input)
+		}
+		signCmd.AddCommand(&cmd)
+	}
+	{
+		cmd := cobra.Command{
+			Use:   "hash <hash>",
+			Short: "Sign raw 32 bytes hash (without any prefix)",
+			Args:  cobra.ExactArgs(1),
+		}
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return signHash(ceth, args[0])
+		}
+		signCmd.AddCommand(&cmd)
+	}
+	{
+		cmd := cobra.Command{
+			Use:   "recover <message> <signature>",
+			Short: "Print the address which created the signature",
+			Args:  cobra.ExactArgs(2),
+		}
+		input := cmd.Flags().String("input", "string", "The interpretation of the message (string,hex,hash). Hash is used without the EIP-191 prefix")
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			recovered, err := recoverMessage(args[0], *input, args[1])
+			if err != nil {
+				return err
+			}
+			fmt.Println(recovered.Hex())
+			return nil
+		}
+		signCmd.AddCommand(&cmd)
+	}
+	{
+		cmd := cobra.Command{
+			Use:     "personal <message> <signature>",
+			Aliases: []string{"text", "message"},
+			Short:   "Verify signature of a message (personal_sign or raw hash)",
+			Args:    cobra.ExactArgs(2),
+		}
+		input := cmd.Flags().String("input", "string", "The interpretation of the message (string,hex,hash). Hash is used without the EIP-191 prefix")
+		address := cmd.Flags().String("address", "", "Expected signer (default: current account)")
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return verifyPersonal(ceth, args[0], *input, args[1], *address)
+		}
+		verifyCmd.AddCommand(&cmd)
+	}
+	RootCmd.AddCommand(&signCmd)
+	RootCmd.AddCommand(&verifyCmd)
+}
+
+func signPersonal(ceth *Ceth, message string, input string) error {
+	msg, err := messageBytes(message, input)
+	if err != nil {
+		return err
+	}
+	signer, err := ceth.GetCurrentSigner()
+	if err != nil {
+		return err
+	}
+	signature, err := personalSign(msg, signer)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hexutil.Encode(signature))
+	return nil
+}
+
+func signHash(ceth *Ceth, hash string) error {
+	h, err := hexutil.Decode(hash)
+	if err != nil {
+		return errors.Wrap(err, "Hash is not a valid hex string")
+	}
+	if len(h) != common.HashLength {
+		return errors.Errorf("Hash should be %d bytes long", common.HashLength)
+	}
+	signer, err := ceth.GetCurrentSigner()
+	if err != nil {
+		return err
+	}
+	signature, err := signer.SignHash(h)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hexutil.Encode(signature))
+	return nil
+}
+
+func verifyPersonal(ceth *Ceth, message string, input string, signature string, address string) error {
+	recovered, err := recoverMessage(message, input, signature)
+	if err != nil {
+		return err
+	}
+	expected, err := ceth.ResolveAddress(address)
+	if err != nil {
+		return err
+	}
+	if recovered != expected {
+		return errors.Errorf("Signature is created by %s instead of %s", recovered.Hex(), expected.Hex())
+	}
+	fmt.Println("Signature is valid (" + recovered.Hex() + ")")
+	return nil
+}
+
+// recoverMessage returns the signer of a personal_sign message, or the signer of raw hash (with input=hash).
+func recoverMessage(message string, input string, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil {
+		return common.Address{}, errors.Wrap(err, "Signature is not a valid hex string")
+	}
+	if input == "hash" {
+		h, err := hexutil.Decode(message)
+		if err != nil {
+			return common.Address{}, errors.Wrap(err, "Hash is not a valid hex string")
+		}
+		return recoverAddress(h, sig)
+	}
+	msg, err := messageBytes(message, input)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return recoverAddress(accounts.TextHash(msg), sig)
+}
+
+func messageBytes(message string, input string) ([]byte, error) {
+	switch input {
+	case "string":
+		return []byte(message), nil
+	case "hex":
+		return encoding.HexToBytes(message)
+	default:
+		return nil, errors.Errorf("Unsupported input type %s. Use string or hex.", input)
+	}
+}
+
+func signTyped(ceth *Ceth, file string) error {
+	typedData, err := readTypedData(file)
+	if err != nil {
+		return err
+	}
+
+	item, err := typedDataItem(typedData)
+	if err != nil {
+		return err
+	}
+
+	signer, err := ceth.GetCurrentSigner()
+	if err != nil {
+		return err
+	}
+	signature, err := signer.SignTypedData(typedData)
+	if err != nil {
+		return err
+	}
+	item.AddField("signer", signer.Address().Hex())
+	item.AddField("signature", hexutil.Encode(signature))
+	return PrintItem(item, ceth.Settings.Format)
+}
+
+func verifyTyped(ceth *Ceth, file string, signature string, address string) error {
+	typedData, err := readTypedData(file)
+	if err != nil {
+		return err
+	}
+
+	item, err := typedDataItem(typedData)
+	if err != nil {
+		return err
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return err
+	}
+
+	sig, err := hexutil.Decode(signature)
+	if err != nil {
+		return errors.Wrap(err, "Signature is not a valid hex string")
+	}
+	recovered, err := recoverAddress(hash, sig)
+	if err != nil {
+		return err
+	}
+
+	expected, err := ceth.ResolveAddress(address)
+	if err != nil {
+		return err
+	}
+	item.AddField("signer", recovered.Hex())
+	item.AddField("valid", recovered == expected)
+	err = PrintItem(item, ceth.Settings.Format)
+	if err != nil {
+		return err
+	}
+	if recovered != expected {
+		return errors.Errorf("Signature is created by %s instead of %s", recovered.Hex(), expected.Hex())
+	}
+	return nil
+}
+
+func readTypedData(file string) (apitypes.TypedData, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return apitypes.TypedData{}, errors.Wrap(err, "Couldn't read typed data file "+file)
+	}
+	return parseTypedData(content)
+}
+
+func parseTypedData(content []byte) (apitypes.TypedData, error) {
+	typedData := apitypes.TypedData{}
+
+	// dapps usually send the chainId as JSON number, but only string is accepted by TypedDataDomain
+	raw := map[string]interface{}{}
+	err := json.Unmarshal(content, &raw)
+	if err != nil {
+		return typedData, errors.Wrap(err, "Invalid EIP-712 typed data")
+	}
+	if domain, ok := raw["domain"].(map[string]interface{}); ok {
+		if chainID, ok := domain["chainId"].(float64); ok {
+			domain["chainId"] = strconv.FormatFloat(chainID, 'f', 0, 64)
+			content, err = json.Marshal(raw)
+			if err != nil {
+				return typedData, err
+			}
+		}
+	}
+
+	err = json.Unmarshal(content, &typedData)
+	if err != nil {
+		return typedData, errors.Wrap(err, "Invalid EIP-712 typed data")
+	}
+	return typedData, nil
+}
+
+// typedDataItem calculates the EIP-712 hashes of the typed data.
+func typedDataItem(typedData apitypes.TypedData) (types.Item, error) {
+	item := types.Item{}
+	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	if err != nil {
+		return item, errors.Wrap(err, "Couldn't calculate domain separator")
+	}
+	structHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return item, errors.Wrap(err, "Couldn't calculate struct hash")
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return item, err
+	}
+	item.AddField("primaryType", typedData.PrimaryType)
+	item.AddField("domainSeparator", domainSeparator.String())
+	item.AddField("structHash", structHash.String())
+	item.AddField("hash", hexutil.Encode(hash))
+	return item, nil
+}
+
+// recoverAddress returns the address of the signer. Both 0/1 and 27/28 V values are accepted.
+func recoverAddress(hash []byte, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, errors.Errorf("Signature should be %d bytes long", crypto.SignatureLength)
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, errors.Wrap(err, "Couldn't recover signer")
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
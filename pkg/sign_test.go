@@ -0,0 +1,47 @@
+package cethacea
+
+import (
+	"github.com/elek/cethacea/pkg/signer"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSignTypedData(t *testing.T) {
+	typedData, err := readTypedData("../examples/mail.json")
+	require.NoError(t, err)
+
+	item, err := typedDataItem(typedData)
+	require.NoError(t, err)
+	require.Equal(t, "0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f", item.GetString("domainSeparator"))
+	require.Equal(t, "0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e", item.GetString("structHash"))
+	require.Equal(t, "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2", item.GetString("hash"))
+
+	key := crypto.ToECDSAUnsafe(crypto.Keccak256([]byte("cow")))
+	signature, err := signer.NewKeySigner(key).SignTypedData(typedData)
+	require.NoError(t, err)
+	require.Equal(t, "0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b915621c", hexutil.Encode(signature))
+
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	require.NoError(t, err)
+	recovered, err := recoverAddress(hash, signature)
+	require.NoError(t, err)
+	require.Equal(t, "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826", recovered.Hex())
+}
+
+func TestRecoverMessage(t *testing.T) {
+	signature := "0x8be981f0d4356c8ad2e32bc9f68384da154cb6f5bdef16306fc8135fe08751604539a8045c838825def79a71c5508650176fd1cf258a003cf74ea5444c9f86ed1c"
+	message := "416363657373207a6b53796e63206163636f756e742e0a0a4f6e6c79207369676e2074686973206d65737361676520666f722061207472757374656420636c69656e7421"
+
+	key, err := crypto.HexToECDSA("72e06ca1f2a055a4f531d48616a744ca9e0682c32035fadd9f56d814a9704309")
+	require.NoError(t, err)
+
+	recovered, err := recoverMessage(message, "hex", signature)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), recovered)
+
+	_, err = recoverMessage(message, "binary", signature)
+	require.Error(t, err)
+}
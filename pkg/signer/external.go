@@ -0,0 +1,107 @@
+package signer
+
+import (
+	"context"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/pkg/errors"
+	"math/big"
+)
+
+// ExternalSigner delegates signing to a separated process which speaks the Clef JSON-RPC API (account_*).
+type ExternalSigner struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+var _ types.Signer = &ExternalSigner{}
+
+func NewExternalSigner(url string, address common.Address) (*ExternalSigner, error) {
+	client, err := rpc.Dial(url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Couldn't connect to external signer %s", url)
+	}
+	return newExternalSigner(client, address), nil
+}
+
+func newExternalSigner(client *rpc.Client, address common.Address) *ExternalSigner {
+	return &ExternalSigner{
+		client:  client,
+		address: address,
+	}
+}
+
+func (e *ExternalSigner) Address() common.Address {
+	return e.address
+}
+
+func (e *ExternalSigner) SignTx(tx *ethtypes.Transaction, chainID *big.Int) (*ethtypes.Transaction, error) {
+	data := hexutil.Bytes(tx.Data())
+	value := new(big.Int)
+	if tx.Value() != nil {
+		value = tx.Value()
+	}
+	args := apitypes.SendTxArgs{
+		From:    common.NewMixedcaseAddress(e.address),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   hexutil.Big(*value),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    &data,
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if tx.To() != nil {
+		to := common.NewMixedcaseAddress(*tx.To())
+		args.To = &to
+	}
+	switch tx.Type() {
+	case ethtypes.DynamicFeeTxType:
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	default:
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	}
+	if tx.Type() != ethtypes.LegacyTxType {
+		accessList := tx.AccessList()
+		args.AccessList = &accessList
+	}
+
+	res := struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}{}
+	err := e.client.CallContext(context.Background(), &res, "account_signTransaction", &args)
+	if err != nil {
+		return nil, errors.Wrap(err, "External signer couldn't sign the transaction")
+	}
+	signed := new(ethtypes.Transaction)
+	err = signed.UnmarshalBinary(res.Raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "External signer returned invalid transaction")
+	}
+	return signed, nil
+}
+
+func (e *ExternalSigner) SignHash(hash []byte) ([]byte, error) {
+	return nil, errors.New("Signing raw hash is not supported by the external signer")
+}
+
+func (e *ExternalSigner) SignText(message []byte) ([]byte, error) {
+	var res hexutil.Bytes
+	err := e.client.CallContext(context.Background(), &res, "account_signData", "text/plain", e.address, hexutil.Encode(message))
+	if err != nil {
+		return nil, errors.Wrap(err, "External signer couldn't sign the message")
+	}
+	return res, nil
+}
+
+func (e *ExternalSigner) SignTypedData(data apitypes.TypedData) ([]byte, error) {
+	var res hexutil.Bytes
+	err := e.client.CallContext(context.Background(), &res, "account_signTypedData", e.address, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "External signer couldn't sign the typed data")
+	}
+	return res, nil
+}
@@ -0,0 +1,73 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+)
+
+// clefStub implements the subset of the Clef account_* API, signing with a local key.
+type clefStub struct {
+	key *ecdsa.PrivateKey
+}
+
+func (c *clefStub) SignTransaction(args apitypes.SendTxArgs) (map[string]interface{}, error) {
+	signed, err := ethtypes.SignTx(args.ToTransaction(), ethtypes.NewLondonSigner((*big.Int)(args.ChainID)), c.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"raw": hexutil.Bytes(raw), "tx": signed}, nil
+}
+
+func (c *clefStub) SignData(contentType string, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
+	return NewKeySigner(c.key).SignText(data)
+}
+
+func (c *clefStub) SignTypedData(addr common.MixedcaseAddress, data apitypes.TypedData) (hexutil.Bytes, error) {
+	return NewKeySigner(c.key).SignTypedData(data)
+}
+
+func TestExternalSigner(t *testing.T) {
+	key, err := crypto.HexToECDSA("72e06ca1f2a055a4f531d48616a744ca9e0682c32035fadd9f56d814a9704309")
+	require.NoError(t, err)
+	local := NewKeySigner(key)
+
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("account", &clefStub{key: key}))
+	external := newExternalSigner(rpc.DialInProc(server), local.Address())
+
+	to := common.HexToAddress("0x158d2c25ba6107b622f288663f50f53601ab6710")
+	chainID := big.NewInt(5)
+	tx := ethtypes.NewTx(&ethtypes.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     3,
+		GasTipCap: big.NewInt(1000),
+		GasFeeCap: big.NewInt(2000),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(42),
+	})
+	signed, err := external.SignTx(tx, chainID)
+	require.NoError(t, err)
+	sender, err := ethtypes.Sender(ethtypes.NewLondonSigner(chainID), signed)
+	require.NoError(t, err)
+	require.Equal(t, local.Address(), sender)
+	require.Equal(t, uint64(3), signed.Nonce())
+
+	expected, err := local.SignText([]byte("hello"))
+	require.NoError(t, err)
+	signature, err := external.SignText([]byte("hello"))
+	require.NoError(t, err)
+	require.Equal(t, expected, signature)
+}
@@ -0,0 +1,60 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/pkg/errors"
+	"math/big"
+)
+
+// KeySigner signs everything with a private key available in the memory.
+type KeySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+var _ types.Signer = &KeySigner{}
+
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{
+		key: key,
+	}
+}
+
+func (k *KeySigner) Address() common.Address {
+	return crypto.PubkeyToAddress(k.key.PublicKey)
+}
+
+func (k *KeySigner) SignTx(tx *ethtypes.Transaction, chainID *big.Int) (*ethtypes.Transaction, error) {
+	return ethtypes.SignTx(tx, ethtypes.NewLondonSigner(chainID), k.key)
+}
+
+func (k *KeySigner) SignHash(hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, k.key)
+}
+
+func (k *KeySigner) SignText(message []byte) ([]byte, error) {
+	signature, err := k.SignHash(accounts.TextHash(message))
+	if err != nil {
+		return nil, err
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
+
+func (k *KeySigner) SignTypedData(data apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "Couldn't hash typed data")
+	}
+	signature, err := k.SignHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
@@ -0,0 +1,96 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/pkg/errors"
+	"math/big"
+)
+
+// KeystoreSigner decrypts the private key only when the first signature is requested.
+type KeystoreSigner struct {
+	address  common.Address
+	decrypt  func() (*ecdsa.PrivateKey, error)
+	unlocked *KeySigner
+}
+
+var _ types.Signer = &KeystoreSigner{}
+
+// NewKeystoreSigner creates signer for a Web3 Secret Storage key.
+func NewKeystoreSigner(address common.Address, content []byte, passphrase func() (string, error)) *KeystoreSigner {
+	return NewLazySigner(address, func() (*ecdsa.PrivateKey, error) {
+		pwd, err := passphrase()
+		if err != nil {
+			return nil, err
+		}
+		key, err := keystore.DecryptKey(content, pwd)
+		if err != nil {
+			return nil, errors.Wrap(err, "Couldn't decrypt the keystore")
+		}
+		return key.PrivateKey, nil
+	})
+}
+
+// NewLazySigner creates signer where the private key is decrypted with the decrypt function (at the first signature).
+func NewLazySigner(address common.Address, decrypt func() (*ecdsa.PrivateKey, error)) *KeystoreSigner {
+	return &KeystoreSigner{
+		address: address,
+		decrypt: decrypt,
+	}
+}
+
+func (k *KeystoreSigner) unlock() (*KeySigner, error) {
+	if k.unlocked != nil {
+		return k.unlocked, nil
+	}
+	key, err := k.decrypt()
+	if err != nil {
+		return nil, err
+	}
+	if decrypted := crypto.PubkeyToAddress(key.PublicKey); decrypted != k.address {
+		return nil, errors.Errorf("Decrypted key belongs to a different address: %s", decrypted)
+	}
+	k.unlocked = NewKeySigner(key)
+	return k.unlocked, nil
+}
+
+func (k *KeystoreSigner) Address() common.Address {
+	return k.address
+}
+
+func (k *KeystoreSigner) SignTx(tx *ethtypes.Transaction, chainID *big.Int) (*ethtypes.Transaction, error) {
+	s, err := k.unlock()
+	if err != nil {
+		return nil, err
+	}
+	return s.SignTx(tx, chainID)
+}
+
+func (k *KeystoreSigner) SignHash(hash []byte) ([]byte, error) {
+	s, err := k.unlock()
+	if err != nil {
+		return nil, err
+	}
+	return s.SignHash(hash)
+}
+
+func (k *KeystoreSigner) SignText(message []byte) ([]byte, error) {
+	s, err := k.unlock()
+	if err != nil {
+		return nil, err
+	}
+	return s.SignText(message)
+}
+
+func (k *KeystoreSigner) SignTypedData(data apitypes.TypedData) ([]byte, error) {
+	s, err := k.unlock()
+	if err != nil {
+		return nil, err
+	}
+	return s.SignTypedData(data)
+}
@@ -5,10 +5,28 @@ import _ "embed"
 //go:embed ERC20.abi
 var erc20 []byte
 
+//go:embed ERC721.abi
+var erc721 []byte
+
+//go:embed u2pair.abi
+var u2pair []byte
+
+//go:embed u2factory.abi
+var u2factory []byte
+
+// Names are the names of the bundled standard ABIs (used to decode events of unknown contracts).
+var Names = []string{"erc20", "erc721", "u2pair", "u2factory"}
+
 func GetPredefinedContract(name string) ([]byte, bool) {
 	switch name {
 	case "@ERC-20", "<ERC-20>", "<ERC20>", "erc20":
 		return erc20, true
+	case "@ERC-721", "<ERC-721>", "<ERC721>", "erc721":
+		return erc721, true
+	case "u2pair":
+		return u2pair, true
+	case "u2factory":
+		return u2factory, true
 	}
 	return nil, false
 }
@@ -3,6 +3,11 @@ package cethacea
 import (
 	"context"
 	"fmt"
+	"github.com/elek/cethacea/pkg/chain"
+	"github.com/elek/cethacea/pkg/encoding"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
 	"math/big"
@@ -123,6 +128,10 @@ func tokenBalance(ceth *Ceth, address string, raw bool, all bool) error {
 		symbol = info.Symbol
 	}
 
+	if all {
+		return allTokenBalances(ceth, contract.GetAddress(), decimals, symbol)
+	}
+
 	amount, err := c.TokenBalance(ctx, contract.GetAddress(), target)
 	if err != nil {
 		return err
@@ -132,6 +141,48 @@ func tokenBalance(ceth *Ceth, address string, raw bool, all bool) error {
 	return nil
 }
 
+// allTokenBalances queries the token balance of all the accounts with batched calls.
+func allTokenBalances(ceth *Ceth, token common.Address, decimals uint8, symbol string) error {
+	accounts, err := ceth.AccountRepo.ListAccounts()
+	if err != nil {
+		return err
+	}
+
+	client, err := ceth.GetClient()
+	if err != nil {
+		return err
+	}
+
+	fs, err := encoding.ParseFunctionSignature("balanceOf(address)uint256")
+	if err != nil {
+		return err
+	}
+	var calls []chain.QueryCall
+	for _, a := range accounts {
+		data, err := fs.EncodeFuncCall(types.WithoutAddressResolution{}, a.Address().Hex())
+		if err != nil {
+			return err
+		}
+		calls = append(calls, chain.QueryCall{Target: token, Data: data})
+	}
+
+	results, err := client.Multicall().Query(context.Background(), calls)
+	if err != nil {
+		return err
+	}
+	for ix, a := range accounts {
+		if results[ix].Err != nil {
+			return errors.Wrap(results[ix].Err, "Couldn't get balance for "+a.Address().String())
+		}
+		values, err := fs.Outputs.Unpack(results[ix].Data)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s %s\n", a.Name, PrintAmount(values[0].(*big.Int), decimals, symbol))
+	}
+	return nil
+}
+
 func PrintAmount(amount *big.Int, dec uint8, symbol string) string {
 	out := amount.String()
 	if dec != 0 && symbol != "" {
@@ -2,13 +2,22 @@ package cethacea
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/elek/cethacea/pkg/chain"
 	"github.com/elek/cethacea/pkg/encoding"
+	"github.com/elek/cethacea/pkg/types"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"io/ioutil"
 	"math/big"
-	"time"
+	"os"
+	"strconv"
 )
 
 func init() {
@@ -50,19 +59,35 @@ func init() {
 	{
 		txCancelCmd := cobra.Command{
 			Use:   "cancel <tx>",
-			Short: "Cancel pending transaction",
+			Short: "Cancel pending transaction (replace it with an empty transaction with higher fees)",
 			Args:  cobra.ExactArgs(1),
-			RunE: func(cmd *cobra.Command, args []string) error {
-				ceth, err := NewCethContext(&Settings)
-				if err != nil {
-					return err
-				}
-
-				return cancelTx(ceth, args[0])
-			},
+		}
+		dryRun := txCancelCmd.Flags().Bool("dry-run", false, "Print the fees of the replacement transaction without sending it")
+		txCancelCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return replaceTx(ceth, args[0], true, *dryRun)
 		}
 		txCommand.AddCommand(&txCancelCmd)
 	}
+	{
+		txSpeedupCmd := cobra.Command{
+			Use:   "speedup <tx>",
+			Short: "Re-send pending transaction with higher fees (same nonce and payload)",
+			Args:  cobra.ExactArgs(1),
+		}
+		dryRun := txSpeedupCmd.Flags().Bool("dry-run", false, "Print the fees of the replacement transaction without sending it")
+		txSpeedupCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return replaceTx(ceth, args[0], false, *dryRun)
+		}
+		txCommand.AddCommand(&txSpeedupCmd)
+	}
 	{
 		txSubmitCmd := cobra.Command{
 			Use:   "submit <tx>",
@@ -73,19 +98,302 @@ func init() {
 		data := txSubmitCmd.Flags().String("data", "", "Hex data of the transaction")
 		to := txSubmitCmd.Flags().String("to", "", "Target address of the transaction")
 		gasTipCap := txSubmitCmd.Flags().Int64("gas-tip-cap", 0, "Gas tip cap used in the transaction (0=use the node oracle)")
+		_ = txSubmitCmd.Flags().MarkDeprecated("gas-tip-cap", "use the global --tip option (like --tip 1.5gwei)")
+		accessList := txSubmitCmd.Flags().Bool("access-list", false, "Add access list (eth_createAccessList) to the transaction if it saves gas")
 		txSubmitCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			if *gasTipCap != 0 {
+				// value in wei, same as the global --tip without unit
+				Settings.GasTipCap = strconv.FormatInt(*gasTipCap, 10)
+			}
 			ceth, err := NewCethContext(&Settings)
 			if err != nil {
 				return err
 			}
 
-			return submit(ceth, *value, *to, *data, *gasTipCap)
+			return submit(ceth, *value, *to, *data, *accessList)
 		}
 		txCommand.AddCommand(&txSubmitCmd)
 	}
+	{
+		txBuildCmd := cobra.Command{
+			Use:   "build",
+			Short: "Create unsigned transaction (JSON) which can be signed offline",
+		}
+		value := txBuildCmd.Flags().String("value", "", "Value of the transaction")
+		data := txBuildCmd.Flags().String("data", "", "Hex data of the transaction")
+		to := txBuildCmd.Flags().String("to", "", "Target address of the transaction")
+		from := txBuildCmd.Flags().String("from", "", "Sender of the transaction (default: current account)")
+		nonce := txBuildCmd.Flags().Int64("nonce", -1, "Nonce of the transaction (-1=use the pending nonce of the node)")
+		out := txBuildCmd.Flags().StringP("out", "o", "", "File to write the unsigned transaction (default: stdout)")
+		txBuildCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return buildTx(ceth, *from, *to, *value, *data, *nonce, *out)
+		}
+		txCommand.AddCommand(&txBuildCmd)
+	}
+	{
+		txSignCmd := cobra.Command{
+			Use:   "sign <unsigned-tx-file>",
+			Short: "Sign transaction created by 'tx build' (without RPC access)",
+			Args:  cobra.ExactArgs(1),
+		}
+		out := txSignCmd.Flags().StringP("out", "o", "", "File to write the signed raw transaction (default: stdout)")
+		txSignCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return signTx(ceth, args[0], *out)
+		}
+		txCommand.AddCommand(&txSignCmd)
+	}
+	{
+		txBroadcastCmd := cobra.Command{
+			Use:   "broadcast <rawhex|file>",
+			Short: "Send signed (RLP encoded) transaction to the chain",
+			Args:  cobra.ExactArgs(1),
+		}
+		txBroadcastCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return broadcastTx(ceth, args[0])
+		}
+		txCommand.AddCommand(&txBroadcastCmd)
+	}
+	{
+		txDecodeCmd := cobra.Command{
+			Use:   "decode <rawhex|file>",
+			Short: "Decode signed (RLP encoded) transaction and its call data",
+			Args:  cobra.ExactArgs(1),
+		}
+		signature := txDecodeCmd.Flags().String("signature", "", "Function signature to decode the call data (eg. 'transfer(address,uint256)')")
+		txDecodeCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
+			return decodeTx(ceth, args[0], *signature, Settings.Format)
+		}
+		txCommand.AddCommand(&txDecodeCmd)
+	}
 	RootCmd.AddCommand(&txCommand)
 }
 
+func buildTx(ceth *Ceth, from string, to string, value string, data string, nonce int64, out string) error {
+	ctx := context.Background()
+
+	client, err := ceth.GetClient()
+	if err != nil {
+		return err
+	}
+
+	sender, err := ceth.ResolveAddress(from)
+	if err != nil {
+		return err
+	}
+
+	var toAddress *common.Address
+	if to != "" {
+		addr, err := ceth.ResolveAddress(to)
+		if err != nil {
+			return err
+		}
+		toAddress = &addr
+	}
+
+	var opts []interface{}
+	if data != "" {
+		hexData, err := encoding.HexToBytes(data)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, chain.WithData{Data: hexData})
+	}
+	if value != "" {
+		v, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return fmt.Errorf("invalid value %s", value)
+		}
+		opts = append(opts, chain.WithValue{Value: v})
+	}
+	if nonce >= 0 {
+		opts = append(opts, chain.WithNonce{Nonce: uint64(nonce)})
+	}
+
+	tx, chainID, err := client.PrepareTransaction(ctx, sender, toAddress, opts...)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(chain.NewUnsignedTx(tx, chainID, sender), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutput(out, content)
+}
+
+func signTx(ceth *Ceth, file string, out string) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errors.Wrap(err, "Couldn't read unsigned transaction "+file)
+	}
+	unsigned := chain.UnsignedTx{}
+	err = json.Unmarshal(content, &unsigned)
+	if err != nil {
+		return errors.Wrap(err, "Unsigned transaction is not a valid JSON")
+	}
+	tx, err := unsigned.Transaction()
+	if err != nil {
+		return err
+	}
+
+	signer, err := ceth.GetCurrentSigner()
+	if err != nil {
+		return err
+	}
+	if unsigned.From != nil && *unsigned.From != signer.Address() {
+		return errors.Errorf("Transaction is prepared for %s, but the current account is %s", unsigned.From.Hex(), signer.Address().Hex())
+	}
+
+	signed, err := signer.SignTx(tx, unsigned.ChainID.ToInt())
+	if err != nil {
+		return errors.Wrap(err, "Couldn't sign the transaction")
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return writeOutput(out, []byte(hexutil.Encode(raw)))
+}
+
+func broadcastTx(ceth *Ceth, rawOrFile string) error {
+	tx, err := readSignedTx(rawOrFile)
+	if err != nil {
+		return err
+	}
+
+	client, err := ceth.GetClient()
+	if err != nil {
+		return err
+	}
+	hash, err := client.SendSignedTransaction(context.Background(), tx)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hash.Hex())
+	return nil
+}
+
+func decodeTx(ceth *Ceth, rawOrFile string, signature string, format string) error {
+	tx, err := readSignedTx(rawOrFile)
+	if err != nil {
+		return err
+	}
+
+	item := types.Item{}
+	item.AddField("hash", tx.Hash().Hex())
+	item.AddField("type", txTypeName(tx.Type()))
+	item.AddField("chainId", tx.ChainId())
+	sender, err := ethtypes.Sender(ethtypes.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		item.AddField("from", "??? "+err.Error())
+	} else {
+		item.AddField("from", sender.Hex())
+	}
+	item.AddField("to", optionalAddress(tx.To()))
+	item.AddField("nonce", tx.Nonce())
+	item.AddField("value", tx.Value())
+	item.AddField("gas", tx.Gas())
+	if tx.Type() == ethtypes.DynamicFeeTxType {
+		item.Fields = append(item.Fields,
+			types.Field{Name: "maxFeePerGas", Value: tx.GasFeeCap(), Printer: types.EthPrintType},
+			types.Field{Name: "maxPriorityFeePerGas", Value: tx.GasTipCap(), Printer: types.EthPrintType})
+	} else {
+		item.Fields = append(item.Fields, types.Field{Name: "gasPrice", Value: tx.GasPrice(), Printer: types.EthPrintType})
+	}
+	if tx.Type() != ethtypes.LegacyTxType {
+		item.AddField("accessList", len(tx.AccessList()))
+	}
+	item.AddField("data", hexutil.Encode(tx.Data()))
+
+	if len(tx.Data()) >= 4 {
+		var contractAbi *abi.ABI
+		if tx.To() != nil {
+			contractAbi = abiOfAddress(ceth, *tx.To(), tx.ChainId().Int64())
+		}
+		if contract, err := ceth.GetCurrentContract(); err == nil && contractAbi == nil && contract.Abi != "" {
+			if parsed, err := contract.GetAbi(); err == nil {
+				contractAbi = &parsed
+			}
+		}
+		methods, err := candidateMethods(signature, contractAbi)
+		if err != nil {
+			return err
+		}
+		method, values, err := decodeCallData(tx.Data(), methods)
+		if err == nil {
+			addCallDataFields(&item, method, values)
+		} else if signature != "" || contractAbi != nil {
+			item.AddField("method", "??? "+err.Error())
+		}
+	}
+	return PrintItem(item, format)
+}
+
+func readSignedTx(rawOrFile string) (*ethtypes.Transaction, error) {
+	rawHex := rawOrFile
+	if _, err := os.Stat(rawOrFile); err == nil {
+		content, err := ioutil.ReadFile(rawOrFile)
+		if err != nil {
+			return nil, err
+		}
+		rawHex = string(content)
+	}
+	raw, err := encoding.HexToBytes(rawHex)
+	if err != nil {
+		return nil, errors.Wrap(err, "Signed transaction is not a valid hex string")
+	}
+	tx := new(ethtypes.Transaction)
+	err = tx.UnmarshalBinary(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "Couldn't decode signed transaction")
+	}
+	return tx, nil
+}
+
+func txTypeName(txType uint8) string {
+	switch txType {
+	case ethtypes.LegacyTxType:
+		return "legacy"
+	case ethtypes.AccessListTxType:
+		return "eip2930"
+	case ethtypes.DynamicFeeTxType:
+		return "eip1559"
+	default:
+		return fmt.Sprintf("%d", txType)
+	}
+}
+
+func optionalAddress(to *common.Address) string {
+	if to == nil {
+		return "<nil>"
+	}
+	return to.Hex()
+}
+
+func writeOutput(file string, content []byte) error {
+	if file == "" {
+		fmt.Println(string(content))
+		return nil
+	}
+	return ioutil.WriteFile(file, append(content, '\n'), 0644)
+}
+
 func debugTx(ceth *Ceth, s string) error {
 	ctx := context.Background()
 
@@ -123,8 +431,10 @@ func debugTx(ceth *Ceth, s string) error {
 	return err
 }
 
-func cancelTx(ceth *Ceth, s string) error {
-	account, client, err := ceth.AccountClient()
+// replaceTx re-sends the pending transaction with the same nonce and bumped fees (see chain.ReplacementFees). Cancel
+// replaces the transaction with an empty transfer to the sender, otherwise the same payload is re-sent (speed up).
+func replaceTx(ceth *Ceth, s string, cancel bool, dryRun bool) error {
+	signer, client, err := ceth.SignerClient()
 	if err != nil {
 		return err
 	}
@@ -132,24 +442,75 @@ func cancelTx(ceth *Ceth, s string) error {
 
 	txHash := common.HexToHash(s)
 	tx, pending, err := client.Client.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return errors.Wrapf(err, "Couldn't get transaction %s", txHash)
+	}
 	if !pending {
-		return fmt.Errorf("transaction %s is not in pending state", txHash)
+		return errors.Errorf("Transaction %s is not in pending state", txHash)
 	}
+	sender, err := ethtypes.Sender(ethtypes.LatestSignerForChainID(tx.ChainId()), tx)
 	if err != nil {
 		return err
 	}
+	if sender != signer.Address() {
+		return errors.Errorf("Transaction is sent by %s, but the current account is %s", sender.Hex(), signer.Address().Hex())
+	}
 
-	to := account.Address()
-	res, err := client.SendTransaction(ctx, account, &to,
-		chain.WithGas{Gas: tx.Gas() + 10},
-		chain.WithNonce{Nonce: tx.Nonce()},
-		chain.WithGasFeeCap{Value: new(big.Int).Add(tx.GasTipCap(), big.NewInt(10))},
-		chain.WithGasTipCap{Value: new(big.Int).Add(tx.GasTipCap(), big.NewInt(10))})
+	market, err := client.FeeMarket(ctx)
 	if err != nil {
 		return err
 	}
-	fmt.Println(res)
-	return nil
+	fees := chain.ReplacementFees(tx, market)
+
+	to := tx.To()
+	opts := []interface{}{chain.WithNonce{Nonce: tx.Nonce()}}
+	if cancel {
+		self := signer.Address()
+		to = &self
+		opts = append(opts, chain.WithGas{Gas: 21000}, chain.WithValue{Value: big.NewInt(0)})
+	} else {
+		opts = append(opts, chain.WithGas{Gas: tx.Gas()}, chain.WithData{Data: tx.Data()}, chain.WithValue{Value: tx.Value()})
+	}
+	opts = append(opts, fees...)
+
+	if dryRun {
+		return PrintItem(replacementItem(tx, market, fees), ceth.Settings.Format)
+	}
+	replacement, err := client.SendTransaction(ctx, signer, to, opts...)
+	if err != nil {
+		return err
+	}
+	fmt.Println(replacement.Hex())
+	return waitAndPrintTx(ceth, client, replacement)
+}
+
+// replacementItem shows the original and the new fees of the replacement transaction.
+func replacementItem(tx *ethtypes.Transaction, market chain.FeeMarket, fees []interface{}) types.Item {
+	item := types.Item{}
+	item.AddField("tx", tx.Hash().Hex())
+	item.AddField("nonce", tx.Nonce())
+	if market.BaseFee != nil {
+		item.Fields = append(item.Fields, types.Field{Name: "baseFee", Value: market.BaseFee, Printer: types.EthPrintType})
+	}
+	for _, fee := range fees {
+		switch f := fee.(type) {
+		case chain.WithTxType:
+			item.AddField("type", txTypeName(f.Type))
+		case chain.WithGasPrice:
+			item.Fields = append(item.Fields,
+				types.Field{Name: "gasPrice", Value: tx.GasPrice(), Printer: types.EthPrintType},
+				types.Field{Name: "newGasPrice", Value: f.Price, Printer: types.EthPrintType})
+		case chain.WithGasTipCap:
+			item.Fields = append(item.Fields,
+				types.Field{Name: "maxPriorityFeePerGas", Value: tx.GasTipCap(), Printer: types.EthPrintType},
+				types.Field{Name: "newMaxPriorityFeePerGas", Value: f.Value, Printer: types.EthPrintType})
+		case chain.WithGasFeeCap:
+			item.Fields = append(item.Fields,
+				types.Field{Name: "maxFeePerGas", Value: tx.GasFeeCap(), Printer: types.EthPrintType},
+				types.Field{Name: "newMaxFeePerGas", Value: f.Value, Printer: types.EthPrintType})
+		}
+	}
+	return item
 }
 
 func showTx(ceth *Ceth, s string, format string) error {
@@ -159,14 +520,47 @@ func showTx(ceth *Ceth, s string, format string) error {
 	if err != nil {
 		return err
 	}
-	tx, err := c.GetTransaction(ctx, hash)
+	details, err := c.GetTransaction(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) && details.Tx != nil {
+		return errors.New("Transaction receipt is not available (pending transaction?)")
+	}
 	if err != nil {
 		return err
 	}
-	return PrintItem(tx, format)
+	item, tx, receipt := details.Item, details.Tx, details.Receipt
+	chainID := tx.ChainId().Int64()
+
+	if tx.To() != nil && len(tx.Data()) >= 4 {
+		if contractAbi := abiOfAddress(ceth, *tx.To(), chainID); contractAbi != nil {
+			methods, err := candidateMethods("", contractAbi)
+			if err != nil {
+				return err
+			}
+			method, values, err := decodeCallData(tx.Data(), methods)
+			if err == nil {
+				addCallDataFields(&item, method, values)
+			} else {
+				item.AddField("method", "??? "+err.Error())
+			}
+		}
+	}
+
+	item.AddField("logs", len(receipt.Logs))
+	err = PrintItem(item, format)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range receipt.Logs {
+		err = PrintItem(decodeLog(ceth, *l, chainID), format)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func submit(ceth *Ceth, value string, to string, data string, gasTipCap int64) error {
+func submit(ceth *Ceth, value string, to string, data string, accessList bool) error {
 	ctx := context.Background()
 
 	client, err := ceth.GetChainClient()
@@ -183,7 +577,7 @@ func submit(ceth *Ceth, value string, to string, data string, gasTipCap int64) e
 		toAddress = &addr
 	}
 
-	account, err := ceth.AccountRepo.GetCurrentAccount()
+	signer, err := ceth.GetCurrentSigner()
 	if err != nil {
 		return err
 	}
@@ -196,11 +590,6 @@ func submit(ceth *Ceth, value string, to string, data string, gasTipCap int64) e
 		}
 		opts = append(opts, chain.WithData{Data: hexData})
 	}
-	if gasTipCap != 0 {
-		opts = append(opts, chain.WithGasTipCap{
-			Value: big.NewInt(gasTipCap),
-		})
-	}
 
 	if value != "" {
 		v := new(big.Int)
@@ -209,18 +598,85 @@ func submit(ceth *Ceth, value string, to string, data string, gasTipCap int64) e
 			Value: v,
 		})
 	}
-	tx, err := client.SendTransaction(ctx, account, toAddress, opts...)
+	if accessList {
+		opts, err = withAccessList(ctx, client, signer.Address(), toAddress, opts)
+		if err != nil {
+			return err
+		}
+	}
+	tx, err := client.SendTransaction(ctx, signer, toAddress, opts...)
 	if err != nil {
 		return err
 	}
+	return waitAndPrintTx(ceth, client, tx)
+}
+
+// withAccessList creates the access list of the transaction, and adds it to the options if it saves gas. Gas usage
+// with and without the access list is printed to the standard error.
+func withAccessList(ctx context.Context, client chain.ChainClient, from common.Address, to *common.Address, opts []interface{}) ([]interface{}, error) {
+	eth, ok := client.(*chain.Eth)
+	if !ok {
+		return nil, errors.New("Access list is supported only by the eth protocol")
+	}
+	estimate, err := eth.CreateAccessList(ctx, from, to, opts...)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Access list: %d address(es), %d storage key(s), gas %d instead of %d (saving %d)\n",
+		len(estimate.AccessList), estimate.AccessList.StorageKeys(), estimate.Gas, estimate.GasWithout, estimate.Savings())
+	if estimate.Savings() <= 0 {
+		fmt.Fprintln(os.Stderr, "Access list doesn't save gas, sending the transaction without it")
+		return opts, nil
+	}
+	return append(opts, chain.WithAccessList{List: estimate.AccessList}), nil
+}
 
-	for i := 0; i < 30; i++ {
-		info, err := client.GetTransaction(ctx, tx)
-		if err == nil {
-			return PrintItem(info, "console")
+// waitAndPrintTx waits for the sent transaction, and prints it out (if it's mined).
+func waitAndPrintTx(ceth *Ceth, client chain.ChainClient, hash common.Hash) error {
+	res, err := waitTx(ceth, client, hash)
+	if err != nil {
+		return err
+	}
+	if res.Receipt != nil {
+		info, err := client.GetTransaction(context.Background(), hash)
+		if err != nil {
+			return err
+		}
+		err = PrintItem(info.Item, "console")
+		if err != nil {
+			return err
 		}
-		time.Sleep(1 * time.Second)
 	}
-	return fmt.Errorf("submitted transaction couldn't been retrieved")
+	return txStatusError(res)
+}
 
+// waitTx waits until the transaction reaches a final state (see --wait-confirmations and --timeout). Progress is
+// printed to the stderr.
+func waitTx(ceth *Ceth, client chain.ChainClient, hash common.Hash) (chain.TxResult, error) {
+	w := client.Waiter()
+	w.Confirmations = ceth.Settings.WaitConfirmations
+	w.Timeout = ceth.Settings.Timeout
+	w.OnStatus = func(r chain.TxResult) {
+		switch r.Status {
+		case chain.TxPending:
+			fmt.Fprintf(os.Stderr, "Transaction %s is pending\n", r.Hash.Hex())
+		case chain.TxMined:
+			fmt.Fprintf(os.Stderr, "Transaction %s is mined (%d/%d confirmations)\n", r.Hash.Hex(), r.Confirmations, w.Confirmations)
+		}
+	}
+	return w.Wait(context.Background(), hash)
+}
+
+// txStatusError returns error for all the final states except the successful confirmation.
+func txStatusError(res chain.TxResult) error {
+	switch res.Status {
+	case chain.TxConfirmed:
+		return nil
+	case chain.TxTimeout:
+		return errors.Errorf("Transaction %s is not confirmed in time (use --timeout to wait longer)", res.Hash.Hex())
+	case chain.TxReplaced:
+		return errors.Errorf("Transaction %s is replaced by an other transaction with the same nonce", res.Hash.Hex())
+	default:
+		return errors.Errorf("Transaction %s is %s", res.Hash.Hex(), res.Status)
+	}
 }
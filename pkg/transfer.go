@@ -28,7 +28,7 @@ func init() {
 }
 
 func nativeTransfer(ceth *Ceth, amount string, to string) error {
-	account, client, err := ceth.AccountClient()
+	signer, client, err := ceth.SignerClient()
 	if err != nil {
 		return err
 	}
@@ -41,7 +41,7 @@ func nativeTransfer(ceth *Ceth, amount string, to string) error {
 	if err != nil {
 		return err
 	}
-	tx, err := client.SendTransaction(ctx, account, &target, chain.WithValue{Value: value})
+	tx, err := client.SendTransaction(ctx, signer, &target, chain.WithValue{Value: value})
 	if err != nil {
 		return err
 	}
@@ -51,7 +51,7 @@ func nativeTransfer(ceth *Ceth, amount string, to string) error {
 
 func tokenTransfer(ceth *Ceth, amount string, to string) error {
 	ctx := context.Background()
-	account, err := ceth.GetCurrentAccount()
+	signer, err := ceth.GetCurrentSigner()
 	if err != nil {
 		return err
 	}
@@ -94,7 +94,7 @@ func tokenTransfer(ceth *Ceth, amount string, to string) error {
 		return err
 	}
 
-	tx, err := cc.SendTransaction(ctx, account,
+	tx, err := cc.SendTransaction(ctx, signer,
 		&ca,
 		chain.WithData{Data: data})
 	if err != nil {
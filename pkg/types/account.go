@@ -10,6 +10,19 @@ type Account struct {
 	Name    string
 	Private string
 	Public  string
+
+	// Keystore is the Web3 Secret Storage (v3) JSON of the encrypted private key.
+	Keystore string `yaml:"keystore,omitempty"`
+
+	// Mnemonic is the BIP-39 seed phrase of HD wallet accounts.
+	Mnemonic string `yaml:"mnemonic,omitempty"`
+	// EncryptedMnemonic is the encrypted BIP-39 seed phrase of HD wallet accounts (Web3 Secret Storage crypto JSON).
+	EncryptedMnemonic string `yaml:"encrypted-mnemonic,omitempty"`
+	// Path is the BIP-32 derivation path of HD wallet accounts (eg. m/44'/60'/0'/0/0).
+	Path string `yaml:"path,omitempty"`
+
+	// Signer is the URL of an external (Clef compatible) signer which holds the private key.
+	Signer string `yaml:"signer,omitempty"`
 }
 
 func (a Account) Address() common.Address {
@@ -27,3 +40,8 @@ func (a Account) PrivateKey() *ecdsa.PrivateKey {
 	pk, _ := crypto.HexToECDSA(a.Private)
 	return pk
 }
+
+// Encrypted returns true if the private key is available only in encrypted form (and not unlocked yet).
+func (a Account) Encrypted() bool {
+	return (a.Keystore != "" || a.EncryptedMnemonic != "") && a.Private == ""
+}
@@ -4,5 +4,25 @@ type ChainConfig struct {
 	Name     string
 	Protocol string
 	RPCURL   string
-	ChainID  int64
+	// URLs are additional RPC endpoints of the same chain.
+	URLs []string `yaml:"urls,omitempty"`
+	// Policy selects the endpoint for each request (failover, round-robin, fastest-head).
+	Policy  string `yaml:"policy,omitempty"`
+	ChainID int64
+	// TxType is the preferred type of the sent transactions (legacy, eip2930 or eip1559, default: eip1559).
+	TxType string `yaml:"txtype,omitempty"`
+}
+
+// Endpoints returns all the configured RPC URLs (RPCURL first).
+func (c ChainConfig) Endpoints() []string {
+	var res []string
+	seen := map[string]bool{}
+	for _, u := range append([]string{c.RPCURL}, c.URLs...) {
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		res = append(res, u)
+	}
+	return res
 }
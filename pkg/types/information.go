@@ -1,6 +1,8 @@
 package types
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"math/big"
 )
@@ -27,11 +29,43 @@ func (f *Field) String() string {
 		return fmt.Sprintf("%d", f.Value)
 	case bool:
 		return fmt.Sprintf("%v", f.Value)
+	case Tuple, []interface{}:
+		raw, err := json.Marshal(f.Value)
+		if err != nil {
+			return fmt.Sprintf("%v", f.Value)
+		}
+		return string(raw)
 	default:
 		return fmt.Sprintf("%s", f.Value)
 	}
 }
 
+// Tuple is an ordered list of named values (like a solidity struct). It's printed as a JSON object.
+type Tuple []Field
+
+func (t Tuple) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteString("{")
+	for ix, f := range t {
+		if ix > 0 {
+			buf.WriteString(",")
+		}
+		key, err := json.Marshal(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteString(":")
+		buf.Write(value)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
 type Record struct {
 	Fields []Field
 }
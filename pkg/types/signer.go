@@ -0,0 +1,25 @@
+package types
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"math/big"
+)
+
+// Signer signs transactions and messages on behalf of one address without exposing the private key.
+type Signer interface {
+	Address() common.Address
+
+	// SignTx returns the signed version of the transaction.
+	SignTx(tx *ethtypes.Transaction, chainID *big.Int) (*ethtypes.Transaction, error)
+
+	// SignHash returns the [R || S || V] signature of a 32 byte hash, where V is 0 or 1.
+	SignHash(hash []byte) ([]byte, error)
+
+	// SignText signs the message with the EIP-191 (personal_sign) prefix. V is 27 or 28.
+	SignText(message []byte) ([]byte, error)
+
+	// SignTypedData signs EIP-712 structured data. V is 27 or 28.
+	SignTypedData(data apitypes.TypedData) ([]byte, error)
+}
@@ -3,8 +3,11 @@ package cethacea
 import (
 	"context"
 	"fmt"
+	"github.com/elek/cethacea/pkg/chain"
+	"github.com/elek/cethacea/pkg/encoding"
 	"github.com/elek/cethacea/pkg/types"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -168,67 +171,137 @@ func uniswapV2Download(ceth *Ceth) error {
 		return err
 	}
 	pairLength := int(res[0].(*big.Int).Int64())
-	for i := 0; i < pairLength; i++ {
-		pair, found := pairs[i]
-		if !found {
-			res, err := ethClient.Query(ctx, types.WithoutAddressResolution{}, acc.Address(), contract.GetAddress(), "allPairs(uint256)address", strconv.Itoa(i))
-			if err != nil {
-				return err
-			}
 
-			pair = &UniPair{
-				Contract: res[0].(common.Address).String(),
-			}
-			pairs[i] = pair
+	mc := ethClient.Multicall()
+	mc.From = acc.Address()
+	for start := 0; start < pairLength; start += uniswapBatchSize {
+		end := start + uniswapBatchSize
+		if end > pairLength {
+			end = pairLength
 		}
 
-		if pair.Token0 == "" {
-			res, err := ethClient.Query(ctx, types.WithoutAddressResolution{}, acc.Address(), common.HexToAddress(pair.Contract), "token0()address")
-			if err != nil {
-				return err
+		var calls []pairQuery
+		for i := start; i < end; i++ {
+			if _, found := pairs[i]; !found {
+				pair := &UniPair{}
+				pairs[i] = pair
+				calls = append(calls, pairQuery{target: contract.GetAddress(), args: []string{strconv.Itoa(i)}, set: func(v interface{}) {
+					pair.Contract = v.(common.Address).String()
+				}})
 			}
+		}
+		err = queryPairs(ctx, mc, "allPairs(uint256)address", calls, false)
+		if err != nil {
+			return err
+		}
 
-			pair.Token0 = res[0].(common.Address).String()
+		calls = nil
+		for i := start; i < end; i++ {
+			pair := pairs[i]
+			if pair.Token0 == "" {
+				calls = append(calls, pairQuery{target: common.HexToAddress(pair.Contract), set: func(v interface{}) {
+					pair.Token0 = v.(common.Address).String()
+				}})
+			}
+		}
+		err = queryPairs(ctx, mc, "token0()address", calls, false)
+		if err != nil {
+			return err
 		}
 
-		if pair.Token1 == "" {
-			res, err := ethClient.Query(ctx, types.WithoutAddressResolution{}, acc.Address(), common.HexToAddress(pair.Contract), "token1()address")
-			if err != nil {
-				return err
+		calls = nil
+		for i := start; i < end; i++ {
+			pair := pairs[i]
+			if pair.Token1 == "" {
+				calls = append(calls, pairQuery{target: common.HexToAddress(pair.Contract), set: func(v interface{}) {
+					pair.Token1 = v.(common.Address).String()
+				}})
 			}
-			pair.Token1 = res[0].(common.Address).String()
+		}
+		err = queryPairs(ctx, mc, "token1()address", calls, false)
+		if err != nil {
+			return err
 		}
 
-		if pair.Symbol0 == "" {
-			res, err := ethClient.Query(ctx, types.WithoutAddressResolution{}, acc.Address(), common.HexToAddress(pair.Token0), "symbol()string")
-			if err != nil {
-				//return errors.Wrap(err, "")
-			} else {
-				pair.Symbol0 = res[0].(string)
+		calls = nil
+		for i := start; i < end; i++ {
+			pair := pairs[i]
+			if pair.Symbol0 == "" {
+				calls = append(calls, pairQuery{target: common.HexToAddress(pair.Token0), set: func(v interface{}) {
+					pair.Symbol0 = v.(string)
+				}})
+			}
+			if pair.Symbol1 == "" {
+				calls = append(calls, pairQuery{target: common.HexToAddress(pair.Token1), set: func(v interface{}) {
+					pair.Symbol1 = v.(string)
+				}})
 			}
 		}
+		err = queryPairs(ctx, mc, "symbol()string", calls, true)
+		if err != nil {
+			return err
+		}
 
-		if pair.Symbol1 == "" {
-			res, err := ethClient.Query(ctx, types.WithoutAddressResolution{}, acc.Address(), common.HexToAddress(pair.Token1), "symbol()string")
-			if err != nil {
-				//return errors.Wrap(err, "")
-			} else {
-				pair.Symbol1 = res[0].(string)
+		for i := start; i < end; i++ {
+			pair := pairs[i]
+			if pair.Name == "" {
+				pair.Name = pair.Symbol0 + pair.Symbol1
 			}
 		}
+		err = persistPairs(pairs)
+		if err != nil {
+			return err
+		}
+		fmt.Println(end)
+	}
+	fmt.Println(pairLength)
+	return nil
+}
 
-		if pair.Name == "" {
-			pair.Name = pair.Symbol0 + pair.Symbol1
+const uniswapBatchSize = 200
+
+// pairQuery is a read-only call, where the first returned value is saved by the set function.
+type pairQuery struct {
+	target common.Address
+	args   []string
+	set    func(interface{})
+}
+
+// queryPairs executes the same function for all the queries with one batch.
+func queryPairs(ctx context.Context, mc *chain.Multicall, function string, queries []pairQuery, ignoreErrors bool) error {
+	if len(queries) == 0 {
+		return nil
+	}
+	fs, err := encoding.ParseFunctionSignature(function)
+	if err != nil {
+		return err
+	}
+	var calls []chain.QueryCall
+	for _, q := range queries {
+		data, err := fs.EncodeFuncCall(types.WithoutAddressResolution{}, q.args...)
+		if err != nil {
+			return err
 		}
-		if i%10 == 0 {
-			err = persistPairs(pairs)
-			if err != nil {
-				return err
+		calls = append(calls, chain.QueryCall{Target: q.target, Data: data})
+	}
+	results, err := mc.Query(ctx, calls)
+	if err != nil {
+		return err
+	}
+	for ix, q := range queries {
+		err := results[ix].Err
+		var values []interface{}
+		if err == nil {
+			values, err = fs.Outputs.Unpack(results[ix].Data)
+		}
+		if err != nil {
+			if ignoreErrors {
+				continue
 			}
+			return errors.Wrapf(err, "%s is failed on %s", function, q.target)
 		}
-		fmt.Println(i)
+		q.set(values[0])
 	}
-	fmt.Println(pairLength)
 	return nil
 }
 
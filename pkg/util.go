@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
+	"github.com/elek/cethacea/pkg/chain"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"math/big"
 )
@@ -89,35 +91,48 @@ func init() {
 		estimateCmd := cobra.Command{
 			Use:   "estimate",
 			Short: "Give gas fee / tip estimation",
-			RunE: func(cmd *cobra.Command, args []string) error {
-				ceth, err := NewCethContext(&Settings)
-				if err != nil {
-					return err
-				}
+		}
+		blocks := estimateCmd.Flags().Uint64("blocks", chain.FeeHistoryBlocks, "Number of the recent blocks used for the tip percentiles")
+		estimateCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			ceth, err := NewCethContext(&Settings)
+			if err != nil {
+				return err
+			}
 
-				client, err := ceth.GetClient()
-				if err != nil {
-					return err
-				}
-				ctx := context.Background()
-				tipCap, err := client.Client.SuggestGasTipCap(ctx)
-				if err != nil {
-					return err
-				}
-				gasPrice, err := client.Client.SuggestGasPrice(ctx)
-				if err != nil {
-					return err
-				}
+			client, err := ceth.GetClient()
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+			tipCap, err := client.Client.SuggestGasTipCap(ctx)
+			if err != nil {
+				return err
+			}
+			gasPrice, err := client.Client.SuggestGasPrice(ctx)
+			if err != nil {
+				return err
+			}
 
-				head, err := client.Client.BlockByNumber(ctx, nil)
-				if err != nil {
-					return err
-				}
-				fmt.Printf("%-25s: %s\n", "Suggested tip", PrintGWei(tipCap))
-				fmt.Printf("%-25s: %s\n", "Suggested gas price", PrintGWei(gasPrice))
-				fmt.Printf("%-25s: %s\n", "Base fee", PrintGWei(head.BaseFee()))
+			head, err := client.Client.BlockByNumber(ctx, nil)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%-25s: %s\n", "Suggested tip", PrintGWei(tipCap))
+			fmt.Printf("%-25s: %s\n", "Suggested gas price", PrintGWei(gasPrice))
+			fmt.Printf("%-25s: %s\n", "Base fee", PrintGWei(head.BaseFee()))
+
+			// legacy chains may not support eth_feeHistory (or don't have base fee)
+			estimate, err := client.EstimateFees(ctx, *blocks)
+			if err != nil {
+				log.Debug().Err(err).Msg("Fee history is not available")
 				return nil
-			},
+			}
+			fmt.Printf("%-25s: %s\n", "Next base fee", PrintGWei(estimate.BaseFee))
+			for _, strategy := range chain.FeeStrategies {
+				tip := estimate.Tips[strategy]
+				fmt.Printf("%-25s: %s (max fee %s)\n", fmt.Sprintf("Tip %s (p%.0f)", strategy, strategy.Percentile()), PrintGWei(tip), PrintGWei(estimate.MaxFee(tip)))
+			}
+			return nil
 		}
 		utilCmd.AddCommand(&estimateCmd)
 	}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/ecdsa"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
@@ -13,8 +12,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/elek/cethacea/pkg/chain"
+	"github.com/elek/cethacea/pkg/types"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/gorilla/websocket"
 	"github.com/manifoldco/promptui"
 	uuid "github.com/nu7hatch/gouuid"
@@ -22,7 +21,6 @@ import (
 	"github.com/spf13/cobra"
 	"math/big"
 	"net/url"
-	"strconv"
 	"strings"
 )
 
@@ -76,7 +74,7 @@ type JsonRpcResponse struct {
 }
 
 func walletConnect(ceth *Ceth, wcUrl string) error {
-	account, err := ceth.AccountRepo.GetCurrentAccount()
+	signer, err := ceth.GetCurrentSigner()
 	if err != nil {
 		return err
 	}
@@ -146,7 +144,7 @@ func walletConnect(ceth *Ceth, wcUrl string) error {
 					"peerId":   uid.String(),
 					"approved": true,
 					"chainId":  params["chainId"],
-					"accounts": []string{account.Address().String()},
+					"accounts": []string{signer.Address().String()},
 				},
 			}
 			if err = confirm(); err != nil {
@@ -156,6 +154,11 @@ func walletConnect(ceth *Ceth, wcUrl string) error {
 		case "eth_sendTransaction":
 			firstParams := jsonRPC.Params.([]interface{})[0]
 			params := firstParams.(map[string]interface{})
+			if params["from"] != nil {
+				if err = checkAddress(params["from"], signer.Address()); err != nil {
+					return nil, err
+				}
+			}
 			to := common.HexToAddress(params["to"].(string))
 			var opts []interface{}
 
@@ -183,7 +186,7 @@ func walletConnect(ceth *Ceth, wcUrl string) error {
 			if err = confirm(); err != nil {
 				return nil, err
 			}
-			hash, err := client.SendTransaction(context.Background(), account, &to, opts...)
+			hash, err := client.SendTransaction(context.Background(), signer, &to, opts...)
 			if err != nil {
 				return nil, err
 			}
@@ -195,6 +198,11 @@ func walletConnect(ceth *Ceth, wcUrl string) error {
 			return resp, nil
 		case "personal_sign":
 			params := jsonRPC.Params.([]interface{})
+			if len(params) > 1 {
+				if err = checkAddress(params[1], signer.Address()); err != nil {
+					return nil, err
+				}
+			}
 			hexMessage := params[0].(string)
 
 			hexMessage = strings.TrimPrefix(hexMessage[2:], "0x")
@@ -206,7 +214,30 @@ func walletConnect(ceth *Ceth, wcUrl string) error {
 			if err = confirm(); err != nil {
 				return nil, err
 			}
-			signature, err := personalSign(message, account.PrivateKey())
+			signature, err := personalSign(message, signer)
+			if err != nil {
+				return nil, err
+			}
+			resp := JsonRpcResponse{
+				Id:      jsonRPC.Id,
+				JsonRPC: "2.0",
+				Result:  "0x" + hex.EncodeToString(signature),
+			}
+			return resp, nil
+		case "eth_signTypedData", "eth_signTypedData_v4":
+			content, err := typedDataRequest(jsonRPC.Params.([]interface{}), signer.Address())
+			if err != nil {
+				return nil, err
+			}
+			typedData, err := parseTypedData(content)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Println("Typed data to sign: " + string(content))
+			if err = confirm(); err != nil {
+				return nil, err
+			}
+			signature, err := signer.SignTypedData(typedData)
 			if err != nil {
 				return nil, err
 			}
@@ -276,16 +307,49 @@ func walletConnect(ceth *Ceth, wcUrl string) error {
 	return nil
 }
 
-func personalSign(message []byte, key *ecdsa.PrivateKey) ([]byte, error) {
-	prefix := []byte("\x19Ethereum Signed Message:\n" + strconv.Itoa(len(message)))
-	var fullMessage = append(prefix, message...)
-	hash := crypto.Keccak256Hash(fullMessage).Bytes()
-	signature, err := crypto.Sign(hash, key)
+func personalSign(message []byte, signer types.Signer) ([]byte, error) {
+	return signer.SignText(message)
+}
+
+// typedDataRequest returns the EIP-712 typed data of an eth_signTypedData(_v4) request (address and data). Legacy (v1)
+// array payload is not supported.
+func typedDataRequest(params []interface{}, address common.Address) ([]byte, error) {
+	if len(params) < 2 {
+		return nil, errors.New("Address and typed data are required")
+	}
+	if _, legacy := params[0].([]interface{}); legacy {
+		return nil, errors.New("Legacy (v1) typed data is not supported, use eth_signTypedData_v4")
+	}
+	err := checkAddress(params[0], address)
 	if err != nil {
 		return nil, err
 	}
-	signature[len(signature)-1] = signature[len(signature)-1] + 27
-	return signature, nil
+	var content []byte
+	switch typed := params[1].(type) {
+	case string:
+		content = []byte(typed)
+	default:
+		content, err = json.Marshal(typed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(content)), "[") {
+		return nil, errors.New("Legacy (v1) typed data is not supported, use eth_signTypedData_v4")
+	}
+	return content, nil
+}
+
+// checkAddress returns an error if the requested address is not the address of the current account.
+func checkAddress(param interface{}, address common.Address) error {
+	requested, ok := param.(string)
+	if !ok || !common.IsHexAddress(requested) {
+		return errors.Errorf("Invalid address in the request: %v", param)
+	}
+	if common.HexToAddress(requested) != address {
+		return errors.Errorf("Signature is requested for %s, but the current account is %s", requested, address)
+	}
+	return nil
 }
 
 func subscribe(c *websocket.Conn, topic string) error {
@@ -3,8 +3,11 @@ package cethacea
 import (
 	"encoding/hex"
 	"fmt"
+	"github.com/elek/cethacea/pkg/signer"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/require"
+	"strings"
 	"testing"
 )
 
@@ -40,9 +43,34 @@ func Test_sign(t *testing.T) {
 	require.NoError(t, err)
 	message, err := hex.DecodeString("416363657373207a6b53796e63206163636f756e742e0a0a4f6e6c79207369676e2074686973206d65737361676520666f722061207472757374656420636c69656e7421")
 	require.NoError(t, err)
-	signature, err := personalSign(message, pk)
+	signature, err := personalSign(message, signer.NewKeySigner(pk))
 	require.NoError(t, err)
 	expected, err := hex.DecodeString("8be981f0d4356c8ad2e32bc9f68384da154cb6f5bdef16306fc8135fe08751604539a8045c838825def79a71c5508650176fd1cf258a003cf74ea5444c9f86ed1c")
 	require.NoError(t, err)
 	require.Equal(t, expected, signature)
 }
+
+func Test_typedDataRequest(t *testing.T) {
+	alice := common.HexToAddress("0x158d2c25ba6107b622f288663f50f53601ab6710")
+	bob := common.HexToAddress("0x8d3637944ca90b47ff2aa814982cf8d81b51f044")
+	typed := map[string]interface{}{"primaryType": "Mail"}
+
+	content, err := typedDataRequest([]interface{}{strings.ToLower(alice.Hex()), typed}, alice)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"primaryType":"Mail"}`, string(content))
+
+	content, err = typedDataRequest([]interface{}{alice.Hex(), `{"primaryType":"Mail"}`}, alice)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"primaryType":"Mail"}`, string(content))
+
+	// request for an other account
+	_, err = typedDataRequest([]interface{}{bob.Hex(), typed}, alice)
+	require.Error(t, err)
+
+	// legacy (v1) format: array of typed values, followed by the address
+	legacy := []interface{}{map[string]interface{}{"type": "string", "name": "message", "value": "hello"}}
+	_, err = typedDataRequest([]interface{}{legacy, alice.Hex()}, alice)
+	require.Error(t, err)
+	_, err = typedDataRequest([]interface{}{alice.Hex(), `[{"type":"string","name":"message","value":"hello"}]`}, alice)
+	require.Error(t, err)
+}